@@ -0,0 +1,106 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/transparency-dev/armored-witness/internal/audit"
+	"golang.org/x/exp/maps"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the audit log",
+	Long:  `This command verifies the audit log, and then writes its entries out as a JSON array or as CSV.`,
+	Run:   export,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().String("format", "json", "The format to export the log in, one of json or csv.")
+	exportCmd.Flags().String("device", "", "If set, only entries for the device with this serial number are exported.")
+	exportCmd.Flags().String("output_file", "", "The file to write the exported log to. If this is not set, then write to stdout.")
+}
+
+func export(cmd *cobra.Command, args []string) {
+	es, _ := readLog(cmd)
+	if d, _ := cmd.Flags().GetString("device"); d != "" {
+		es = slices.DeleteFunc(es, func(e audit.Entry) bool { return e.Device != d })
+	}
+
+	var w io.Writer = os.Stdout
+	if out, _ := cmd.Flags().GetString("output_file"); out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			log.Fatalf("Failed to create output file: %v", err)
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				log.Fatalf("Failed to close output file: %v", err)
+			}
+		}()
+		w = f
+	}
+
+	var err error
+	switch format, _ := cmd.Flags().GetString("format"); format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(es)
+	case "csv":
+		err = writeCSV(w, es)
+	default:
+		log.Fatalf("Unknown format %q", format)
+	}
+	if err != nil {
+		log.Fatalf("Failed to export audit log: %v", err)
+	}
+}
+
+// writeCSV writes the entries as CSV, with the details of each entry in a single column
+// of space separated key=value pairs.
+func writeCSV(w io.Writer, es []audit.Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"seq", "time", "tool", "device", "event", "details", "prev"}); err != nil {
+		return err
+	}
+	for _, e := range es {
+		keys := maps.Keys(e.Details)
+		slices.Sort(keys)
+		details := []string{}
+		for _, k := range keys {
+			details = append(details, fmt.Sprintf("%s=%s", k, e.Details[k]))
+		}
+		if err := cw.Write([]string{strconv.FormatUint(e.Seq, 10), e.Time.Format(time.RFC3339), e.Tool, e.Device, e.Event, strings.Join(details, " "), e.Prev}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,68 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cmd contains commands for the audit tool.
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/transparency-dev/armored-witness/internal/audit"
+)
+
+// rootCmd represents the base command when called without any subcommands
+var rootCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "A tool for working with the provisioning audit log",
+	Long: `Audit is a tool for working with the provisioning audit log.
+
+The provision and verify tools append an entry to the audit log for everything
+they do to a device. Each entry commits to the hash of the entry before it, so
+the log is tamper-evident.`,
+}
+
+// Execute adds all child commands to the root command and sets flags appropriately.
+// This is called by main.main(). It only needs to happen once to the rootCmd.
+func Execute() {
+	err := rootCmd.Execute()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+func init() {
+	def, _ := audit.DefaultPath()
+	rootCmd.PersistentFlags().String("log", def, "The audit log file.")
+}
+
+// readLog reads and verifies the audit log given by the --log flag, and returns its entries
+// and head hash.
+func readLog(cmd *cobra.Command) ([]audit.Entry, string) {
+	p, _ := cmd.Flags().GetString("log")
+	if p == "" {
+		log.Fatal("Flag log must be specified")
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		log.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	es, head, err := audit.Verify(f)
+	if err != nil {
+		log.Fatalf("Audit log %s failed verification: %v", p, err)
+	}
+	return es, head
+}
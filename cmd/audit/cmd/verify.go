@@ -0,0 +1,54 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"log"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/transparency-dev/armored-witness/internal/audit"
+)
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the hash chain of the audit log",
+	Long: `This command verifies that every entry in the audit log commits to the entry before it.
+
+It prints the hash of the last entry, which commits to the whole log. Recording this
+hash elsewhere allows later removal of entries from the end of the log to be detected,
+by passing it to the --head flag.`,
+	Run: verifyLog,
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().String("head", "", "If set, the hex encoded hash which a previous run of this command reported for the last entry of the log. The log must still contain that entry.")
+}
+
+func verifyLog(cmd *cobra.Command, args []string) {
+	es, head := readLog(cmd)
+	p, _ := cmd.Flags().GetString("log")
+
+	if want, _ := cmd.Flags().GetString("head"); want != "" && want != head {
+		// The entry with hash want must be followed by another, since it's not the head.
+		if !slices.ContainsFunc(es, func(e audit.Entry) bool { return e.Prev == want }) {
+			log.Fatalf("Audit log %s does not contain the entry with hash %s", p, want)
+		}
+	}
+	log.Printf("Audit log verified ok: %d entries, head %s", len(es), head)
+}
@@ -0,0 +1,25 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The audit tool verifies and exports the audit log written by the provision and
+// verify tools.
+package main
+
+import (
+	"github.com/transparency-dev/armored-witness/cmd/audit/cmd"
+)
+
+func main() {
+	cmd.Execute()
+}
@@ -0,0 +1,95 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// authorize_fuse is a tool which creates the signed fuse authorizations required by the
+// provision tool before it will HAB fuse devices.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/transparency-dev/armored-witness/internal/fuseauth"
+	"golang.org/x/mod/sumdb/note"
+	"k8s.io/klog/v2"
+)
+
+const usageString = `
+This program creates a fuse authorization, permitting the devices with the given
+serial numbers to be HAB fused to the --hab_target release environment by the
+provision tool until the authorization expires:
+$ authorize_fuse --signer_key=<key file> --hab_target=<environment> --output=<file> <serial>...
+
+It should be run by an approver, using their own key, rather than by the operator
+who will fuse the devices.
+`
+
+var (
+	signerKey = flag.String("signer_key", "", "Path to a file containing the approver's note signer key.")
+	habTarget = flag.String("hab_target", "", "Release environment the devices may be fused to.")
+	expiresIn = flag.Duration("expires_in", 24*time.Hour, "How long the authorization may be used for.")
+	output    = flag.String("output", "", "Path to write the signed authorization to.")
+)
+
+func main() {
+	klog.InitFlags(nil)
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), usageString+"\n\n")
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "Flags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *signerKey == "" || *habTarget == "" || *output == "" {
+		klog.Exit("--signer_key, --hab_target, and --output are required.")
+	}
+	if flag.NArg() == 0 {
+		klog.Exit("At least one device serial number must be provided.")
+	}
+	k, err := os.ReadFile(*signerKey)
+	if err != nil {
+		klog.Exitf("Failed to read signer key: %v", err)
+	}
+	s, err := note.NewSigner(strings.TrimSpace(string(k)))
+	if err != nil {
+		klog.Exitf("Invalid signer key: %v", err)
+	}
+
+	a := &fuseauth.Authorization{
+		HABTarget: *habTarget,
+		Expires:   time.Now().Add(*expiresIn).UTC().Truncate(time.Second),
+		Serials:   flag.Args(),
+	}
+	b, err := fuseauth.Sign(a, s)
+	if err != nil {
+		klog.Exitf("❌ Failed to create authorization: %v", err)
+	}
+	// Refuse to clobber an existing file, it may be an authorization someone is relying on.
+	f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		klog.Exitf("Failed to create authorization file: %v", err)
+	}
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		klog.Exitf("❌ Failed to write authorization: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		klog.Exitf("❌ Failed to close authorization: %v", err)
+	}
+	klog.Infof("✅ Authorized fusing %d devices to %q until %s, written to %s", len(a.Serials), a.HABTarget, a.Expires.Format(time.RFC3339), *output)
+}
@@ -0,0 +1,181 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// export is a tool which fetches the latest firmware from the firmware transparency log,
+// and writes it into a firmware pack which can be used by the provision and verify tools
+// on machines which have no network access.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+
+	"k8s.io/klog/v2"
+
+	"github.com/transparency-dev/armored-witness-common/release/firmware/ftlog"
+	"github.com/transparency-dev/armored-witness-common/release/firmware/update"
+	"github.com/transparency-dev/armored-witness/internal/fetcher"
+	"github.com/transparency-dev/armored-witness/internal/pack"
+	"github.com/transparency-dev/armored-witness/internal/policy"
+	"github.com/transparency-dev/armored-witness/internal/release"
+	"github.com/transparency-dev/serverless-log/client"
+	"golang.org/x/exp/maps"
+	"golang.org/x/mod/sumdb/note"
+)
+
+var (
+	template            = flag.String("template", "", fmt.Sprintf("One of the optional preconfigured templates (%v), or a template defined in --template_file.", maps.Keys(release.Templates)))
+	templateFile        = flag.String("template_file", "", "Path to a JSON file, or a directory of JSON files, defining additional templates. The files must be pinned with --template_sha256 or signed by --template_verifier.")
+	templateSHA256      = flag.String("template_sha256", "", "Comma separated list of hex encoded SHA256 hashes, one of which each --template_file must match.")
+	templateVerifier    = flag.String("template_verifier", "", "Verifier string for the key which each --template_file must be a signed note from.")
+	firmwareLogURL      = flag.String("firmware_log_url", "", "URL of the firmware transparency log to scan for firmware artefacts.")
+	firmwareLogOrigin   = flag.String("firmware_log_origin", "", "Origin string for the firmware transparency log.")
+	firmwareLogVerifier = flag.String("firmware_log_verifier", "", "Checkpoint verifier key for the firmware transparency log.")
+	binariesURL         = flag.String("binaries_url", "", "Base URL for fetching firmware artefacts referenced by FT log.")
+
+	appletVerifier   = flag.String("applet_verifier", "", "Verifier key for the applet manifest.")
+	bootVerifier     = flag.String("boot_verifier", "", "Verifier key for the boot manifest.")
+	osVerifier1      = flag.String("os_verifier_1", "", "Verifier key 1 for the OS manifest.")
+	osVerifier2      = flag.String("os_verifier_2", "", "Verifier key 2 for the OS manifest.")
+	recoveryVerifier = flag.String("recovery_verifier", "", "Verifier key for the recovery manifest.")
+
+	witnessVerifiers = flag.String("witness_verifiers", "", "Comma separated list of verifier strings for witnesses, at least --witness_threshold of which must have cosigned the FT log checkpoint the firmware pack is exported at.")
+	witnessThreshold = flag.Int("witness_threshold", 0, "Number of --witness_verifiers which must have cosigned the FT log checkpoint.")
+
+	habTarget  = flag.String("hab_target", "", "Device type firmware must be targetting.")
+	habSRKHash = flag.String("hab_srk_hash", "", "Hex encoded SRK hash of the HAB PKI for --hab_target. If set, firmware built with a different SRK hash is rejected.")
+
+	output = flag.String("output", "", "Path to write the firmware pack to.")
+)
+
+func applyFlagTemplate(k string) {
+	src := release.TemplateSource{
+		Path:     *templateFile,
+		SHA256:   release.ParseHashes(*templateSHA256),
+		Verifier: *templateVerifier,
+	}
+	if err := src.Apply(k, release.FlagSet(flag.CommandLine), nil); err != nil {
+		klog.Exitf("Failed to apply template %q: %v", k, err)
+	}
+}
+
+func main() {
+	klog.InitFlags(nil)
+	flag.Parse()
+	if *template != "" {
+		applyFlagTemplate(*template)
+	}
+	if *output == "" {
+		klog.Exit("--output is required.")
+	}
+	ctx := context.Background()
+
+	// Packs are checked against the witness policy wherever they're used, so there's no
+	// point in exporting one which doesn't satisfy it.
+	wp, err := policy.FromFlags(*witnessVerifiers, *witnessThreshold)
+	if err != nil {
+		klog.Exitf("Invalid witness policy: %v", err)
+	}
+	pv, err := pack.VerifierFromFlags(release.FlagSet(flag.CommandLine), wp)
+	if err != nil {
+		klog.Exitf("Invalid flags: %v", err)
+	}
+	p, err := fetchPack(ctx, pv)
+	if err != nil {
+		klog.Exitf("❌ Failed to fetch firmware: %v", err)
+	}
+	if _, err := pv.Verify(p); err != nil {
+		klog.Exitf("❌ Fetched firmware failed verification: %v", err)
+	}
+
+	// Refuse to clobber an existing file, it's likely a pack someone is relying on.
+	f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		klog.Exitf("Failed to create firmware pack: %v", err)
+	}
+	if err := pack.Write(f, p); err != nil {
+		_ = f.Close()
+		klog.Exitf("❌ Failed to write firmware pack: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		klog.Exitf("❌ Failed to close firmware pack: %v", err)
+	}
+	klog.Infof("✅ Firmware pack written to %s", *output)
+}
+
+// fetchPack scans the FT log for the latest firmware of each type, and returns it as a pack.
+func fetchPack(ctx context.Context, pv *pack.Verifier) (*pack.Pack, error) {
+	logBaseURL, err := url.Parse(*firmwareLogURL)
+	if err != nil {
+		return nil, fmt.Errorf("firmware log URL invalid: %v", err)
+	}
+	binBaseURL, err := url.Parse(*binariesURL)
+	if err != nil {
+		return nil, fmt.Errorf("binaries URL invalid: %v", err)
+	}
+
+	logFetcher := fetcher.New(logBaseURL)
+	mv := pv.ManifestVerifiers
+	updateFetcher, err := update.NewFetcher(ctx,
+		update.FetcherOpts{
+			LogFetcher:       logFetcher,
+			LogOrigin:        pv.LogOrigin,
+			LogVerifier:      pv.LogVerifier,
+			BinaryFetcher:    fetcher.BinaryFetcher(fetcher.New(binBaseURL)),
+			AppletVerifier:   mv[ftlog.ComponentApplet][0],
+			BootVerifier:     mv[ftlog.ComponentBoot][0],
+			OSVerifiers:      [2]note.Verifier{mv[ftlog.ComponentOS][0], mv[ftlog.ComponentOS][1]},
+			RecoveryVerifier: mv[ftlog.ComponentRecovery][0],
+			HABTarget:        pv.HABTarget,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("NewFetcher: %v", err)
+	}
+	if err := updateFetcher.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("updateFetcher.Scan: %v", err)
+	}
+
+	p := &pack.Pack{}
+	if p.Boot, err = updateFetcher.GetBoot(ctx); err != nil {
+		return nil, fmt.Errorf("GetBoot: %v", err)
+	}
+	klog.Infof("Found Bootloader bundle @ %d", p.Boot.Index)
+	if p.Recovery, err = updateFetcher.GetRecovery(ctx); err != nil {
+		return nil, fmt.Errorf("GetRecovery: %v", err)
+	}
+	klog.Infof("Found Recovery bundle @ %d", p.Recovery.Index)
+	if p.OS, err = updateFetcher.GetOS(ctx); err != nil {
+		return nil, fmt.Errorf("GetOS: %v", err)
+	}
+	klog.Infof("Found OS bundle @ %d", p.OS.Index)
+	if p.Applet, err = updateFetcher.GetApplet(ctx); err != nil {
+		return nil, fmt.Errorf("GetApplet: %v", err)
+	}
+	klog.Infof("Found Applet bundle @ %d", p.Applet.Index)
+
+	// The leaf hashes allow checkpoints stored on devices to be checked for consistency
+	// with the pack offline.
+	cp, err := pv.Checkpoint(p)
+	if err != nil {
+		return nil, err
+	}
+	if p.LeafHashes, err = client.FetchLeafHashes(ctx, logFetcher, 0, cp.Size, cp.Size); err != nil {
+		return nil, fmt.Errorf("failed to fetch leaf hashes: %v", err)
+	}
+	klog.Infof("Fetched %d leaf hashes", len(p.LeafHashes))
+	return p, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"k8s.io/klog/v2"
+
+	"github.com/transparency-dev/armored-witness/internal/audit"
+)
+
+// auditLog records everything done to devices by this tool, or is nil if no audit log
+// is being kept.
+var auditLog *audit.Log
+
+// openAuditLog opens the audit log given by the --audit_log flag, or the default audit
+// log if the flag isn't set.
+func openAuditLog() (*audit.Log, error) {
+	p := *auditLogPath
+	if p == "" {
+		var err error
+		if p, err = audit.DefaultPath(); err != nil {
+			return nil, fmt.Errorf("unable to determine default audit log location, use --audit_log: %v", err)
+		}
+	}
+	l, err := audit.Open(p, "provision")
+	if err != nil {
+		return nil, err
+	}
+	klog.Infof("Recording audit log in %s", p)
+	return l, nil
+}
+
+// writeAudit appends an entry to the audit log, if there is one.
+//
+// Failing to write to the audit log is reported, but doesn't stop provisioning, as it may
+// happen part way through an irreversible process.
+func writeAudit(device, event string, details map[string]string) {
+	if auditLog == nil {
+		return
+	}
+	if err := auditLog.Append(device, event, details); err != nil {
+		klog.Errorf("❌ Failed to record %s event for device %q in audit log: %v", event, device, err)
+	}
+}
+
+// audit appends an entry about the device being provisioned by p to the audit log.
+func (p *provisioner) audit(event string, details map[string]string) {
+	if p.port != "" {
+		if details == nil {
+			details = map[string]string{}
+		}
+		details["port"] = p.port
+	}
+	writeAudit(p.journal.Serial, event, details)
+}
+
+// flashedDetails returns the audit log details recording that the image in j has been flashed.
+func flashedDetails(j flashJob) map[string]string {
+	return map[string]string{
+		"image":  j.name,
+		"block":  fmt.Sprintf("0x%x", j.block),
+		"size":   fmt.Sprintf("%d", len(j.img)),
+		"sha256": sha256Hex(j.img),
+	}
+}
+
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// attach associates the provisioner's journal with the device with the given serial number,
+// and records that the device has been detected.
+func (p *provisioner) attach(serial string) error {
+	if err := p.journal.attach(p.journalDir, serial); err != nil {
+		return err
+	}
+	if !p.detected {
+		p.detected = true
+		p.audit(audit.EventDetected, nil)
+	}
+	return nil
+}
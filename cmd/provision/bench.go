@@ -0,0 +1,137 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/transparency-dev/armored-witness/internal/device"
+	"github.com/transparency-dev/armored-witness/internal/operator"
+)
+
+// bench tracks the devices being provisioned concurrently in bench mode.
+//
+// Devices are identified by the USB port they're connected to, since this stays the
+// same as the device is rebooted between SDP, recovery, and witness modes.
+type bench struct {
+	fw         *firmwares
+	journalDir string
+	rec        *recorder
+	op         operator.Operator
+
+	wg sync.WaitGroup
+
+	mu sync.Mutex
+	// ports holds the USB ports which have a provisioning worker assigned to them.
+	// The value is true once the worker has finished.
+	ports     map[string]bool
+	succeeded int
+	failed    int
+}
+
+// runBench waits for devices to appear in SDP mode, and provisions each of them concurrently
+// until the context becomes done.
+func runBench(ctx context.Context, fw *firmwares, journalDir string, rec *recorder, op operator.Operator) error {
+	b := &bench{
+		fw:         fw,
+		journalDir: journalDir,
+		rec:        rec,
+		op:         op,
+		ports:      make(map[string]bool),
+	}
+	op.Instruct("", operator.Instruction{Switch: operator.BootSwitchUSB, Action: "connect unprovisioned devices; press Ctrl-C when done"})
+
+	for {
+		select {
+		case <-ctx.Done():
+			klog.Info("Waiting for in-progress provisioning to stop...")
+			b.wg.Wait()
+			klog.Infof("Provisioned %d devices, %d failed", b.succeeded, b.failed)
+			if b.failed > 0 {
+				return fmt.Errorf("failed to provision %d devices", b.failed)
+			}
+			return nil
+		case <-time.After(time.Second):
+			if err := b.poll(ctx); err != nil {
+				klog.Warningf("Failed to detect devices: %v", err)
+			}
+		}
+	}
+}
+
+// poll starts a provisioning worker for each device in SDP mode on a USB port which doesn't
+// already have one.
+func (b *bench) poll(ctx context.Context) error {
+	targets, err := device.DetectHID()
+	if err != nil {
+		return err
+	}
+	present := make(map[string]bool)
+	for _, t := range targets {
+		port, err := device.USBPort(t.DeviceInfo.Path)
+		if err != nil {
+			klog.Warningf("⚠️  Ignoring device %q: %v", t.DeviceInfo.Path, err)
+			continue
+		}
+		present[port] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for port, finished := range b.ports {
+		// Only release a port once its device has left SDP mode, otherwise a device
+		// which failed before booting into recovery would be picked up again forever.
+		if finished && !present[port] {
+			delete(b.ports, port)
+		}
+	}
+	for port := range present {
+		if _, ok := b.ports[port]; ok {
+			continue
+		}
+		p, err := newProvisioner(b.fw, b.journalDir, b.rec, b.op)
+		if err != nil {
+			return err
+		}
+		p.port = port
+		b.ports[port] = false
+		b.wg.Add(1)
+		go b.provision(ctx, p)
+	}
+	return nil
+}
+
+// provision runs the provisioner to completion, and records the outcome.
+func (b *bench) provision(ctx context.Context, p *provisioner) {
+	defer b.wg.Done()
+	p.infof("Starting provisioning")
+	err := p.run(ctx)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ports[p.port] = true
+	if err != nil {
+		b.failed++
+		p.warningf("❌ Failed to provision device %s: %v", p.journal.Serial, err)
+		return
+	}
+	b.succeeded++
+	p.infof("✅ Device %s provisioned!", p.journal.Serial)
+}
@@ -0,0 +1,149 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/transparency-dev/armored-witness/internal/fuseauth"
+	"golang.org/x/mod/sumdb/note"
+	"k8s.io/klog/v2"
+)
+
+// fuseConfirmChars is the number of characters from the end of the device serial number
+// which the operator must type to confirm that a device should be fused.
+const fuseConfirmChars = 4
+
+// fusePlan returns a description of the device about to be fused by p, and what it will
+// be left with, for the operator to check before fusing goes ahead.
+func (p *provisioner) fusePlan() string {
+	s := p.status
+	hab := "not fused"
+	if s.HAB {
+		hab = "fused"
+	}
+	b := &strings.Builder{}
+	fmt.Fprintln(b, "Fuse plan:")
+	fmt.Fprintf(b, "  Device serial:     %s\n", s.Serial)
+	fmt.Fprintf(b, "  Current HAB state: %s\n", hab)
+	fmt.Fprintf(b, "  SRK hash:          %s\n", s.SRKHash)
+	fmt.Fprintf(b, "  Environment:       %s\n", *habTarget)
+	fmt.Fprintf(b, "  Firmware:          boot @ %d, os @ %d, applet @ %d\n", p.journal.Firmware.Boot, p.journal.Firmware.OS, p.journal.Firmware.Applet)
+	return b.String()
+}
+
+// authorizeFuse checks that the fuse authorization given via the --fuse_authorization
+// flag permits the device being provisioned by p to be fused, and unless --fuse_unattended
+// is set, that the operator confirms it too by typing the end of the serial number.
+//
+// The operator's confirmation is in addition to the authorization rather than an alternative
+// to it, since it doesn't provide dual control.
+//
+// The authorization is stored in the journal, so that it ends up in the provisioning record.
+func (p *provisioner) authorizeFuse(ctx context.Context) error {
+	serial := p.journal.Serial
+	a, raw, err := readFuseAuthorization(*fuseAuthorization, fuseApprovers)
+	if err != nil {
+		return err
+	}
+	if err := a.Permits(serial, *habTarget, time.Now()); err != nil {
+		return fmt.Errorf("fuse authorization %s does not permit fusing: %v", *fuseAuthorization, err)
+	}
+	p.infof("✅ Fusing device %s to %q is authorized by %s", serial, *habTarget, *fuseAuthorization)
+	p.journal.FuseAuthorization = string(raw)
+	if *fuseUnattended {
+		return nil
+	}
+
+	want := serial
+	if len(want) > fuseConfirmChars {
+		want = want[len(want)-fuseConfirmChars:]
+	}
+	return p.op.ConfirmTyped(ctx, p.port,
+		fmt.Sprintf("permanently fuse device %s", serial),
+		fmt.Sprintf("the last %d characters of the serial number in the fuse plan", len(want)),
+		want)
+}
+
+// fuseApprovers are the approvers whose fuse authorizations are accepted, as listed in the
+// release template. It's only set when fusing.
+var fuseApprovers note.Verifiers
+
+// fuseApproversFromTemplate returns the fuse approvers listed in the release template named
+// by --template.
+//
+// The approvers deliberately can't be given by flag, since the operator could then approve
+// their own fusing. Instead they come from a template, which is either compiled-in, or
+// pinned or signed.
+func fuseApproversFromTemplate() (note.Verifiers, error) {
+	if *template == "" {
+		return nil, errors.New("fusing requires a --template which lists the fuse approvers for the release environment")
+	}
+	t, err := templateSource().Lookup(*template)
+	if err != nil {
+		return nil, err
+	}
+	v, err := t.FuseApprovers()
+	if err != nil {
+		return nil, fmt.Errorf("template %q does not permit fusing: %v", *template, err)
+	}
+	return v, nil
+}
+
+// readFuseAuthorization reads the fuse authorization at path, and verifies that it's signed
+// by one of approvers, returning both the parsed authorization and the signed note it
+// came from.
+func readFuseAuthorization(path string, approvers note.Verifiers) (*fuseauth.Authorization, []byte, error) {
+	if approvers == nil {
+		return nil, nil, errors.New("no fuse approvers are configured")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read fuse authorization: %v", err)
+	}
+	a, err := fuseauth.Open(b, approvers)
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, b, nil
+}
+
+// checkFuseAuthorization checks, before any devices are touched, that the fuse authorization
+// can be used to fuse devices to --hab_target, and that the operator, who signs provisioning
+// records, isn't one of the approvers.
+func checkFuseAuthorization(rec *recorder) error {
+	a, _, err := readFuseAuthorization(*fuseAuthorization, fuseApprovers)
+	if err != nil {
+		return err
+	}
+	if a.HABTarget != *habTarget {
+		return fmt.Errorf("authorization is for release environment %q, not %q", a.HABTarget, *habTarget)
+	}
+	if time.Now().After(a.Expires) {
+		return fmt.Errorf("authorization expired at %s", a.Expires.Format(time.RFC3339))
+	}
+	if rec != nil {
+		if _, err := fuseApprovers.Verifier(rec.signer.Name(), rec.signer.KeyHash()); err == nil {
+			return fmt.Errorf("--record_signer_key %q is one of the fuse approvers, the operator can't approve their own fusing", rec.signer.Name())
+		}
+	}
+	klog.Infof("Fuse authorization permits fusing %d devices to %q until %s", len(a.Serials), a.HABTarget, a.Expires.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/transparency-dev/armored-witness/internal/fuseauth"
+	"github.com/transparency-dev/armored-witness/internal/release"
+	"golang.org/x/mod/sumdb/note"
+)
+
+func writeAuthorization(t *testing.T, name string) (path string, verifier string) {
+	t.Helper()
+	skey, vkey, err := note.GenerateKey(rand.Reader, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := note.NewSigner(skey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fuseauth.Sign(&fuseauth.Authorization{
+		HABTarget: "prod",
+		Expires:   time.Now().Add(time.Hour),
+		Serials:   []string{"0123456789ABCDEF"},
+	}, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path = filepath.Join(t.TempDir(), name+".auth")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path, vkey
+}
+
+func TestReadFuseAuthorization(t *testing.T) {
+	approved, approver := writeAuthorization(t, "approver")
+	// The operator can create their own key and authorization, but mustn't be able to
+	// use it.
+	selfSigned, _ := writeAuthorization(t, "operator")
+
+	approvers, err := release.Template{release.KeyFuseApprovers: approver}.FuseApprovers()
+	if err != nil {
+		t.Fatalf("FuseApprovers: %v", err)
+	}
+
+	for _, test := range []struct {
+		desc      string
+		path      string
+		approvers note.Verifiers
+		wantErr   bool
+	}{
+		{desc: "signed by approver", path: approved, approvers: approvers},
+		{desc: "self-signed", path: selfSigned, approvers: approvers, wantErr: true},
+		{desc: "no approvers", path: approved, wantErr: true},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			a, _, err := readFuseAuthorization(test.path, test.approvers)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("readFuseAuthorization: got err %v, want err %t", err, test.wantErr)
+			}
+			if err == nil && a.HABTarget != "prod" {
+				t.Errorf("HABTarget = %q, want %q", a.HABTarget, "prod")
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/klog/v2"
+
+	"github.com/transparency-dev/armored-witness/internal/layout"
+)
+
+// writeImage creates a new sparse MMC disk image at the given path, and flashes
+// the bootloader, bootloader config, TrustedOS, and TrustedApplet into it.
+//
+// The image is laid out exactly as the MMC on a device provisioned by this tool,
+// with an empty applet data storage area, and is layout.TotalSize bytes long.
+func writeImage(path string, fw *firmwares) error {
+	jobs, err := prepareFlashJobs(fw)
+	if err != nil {
+		return fmt.Errorf("failed to prepare flash jobs: %v", err)
+	}
+
+	// Refuse to clobber an existing file, it may well be a real block device.
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create image file: %v", err)
+	}
+	// Truncating the file to size without writing any data leaves the unwritten
+	// regions as holes on filesystems which support sparse files.
+	if err := f.Truncate(layout.TotalSize); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to size image file: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close image file: %v", err)
+	}
+
+	klog.Infof("Writing images to %s...", path)
+	if err := flashImages(path, []flashJob{jobs.trustedOS, jobs.bootloader, jobs.bootloaderConfig, jobs.trustedApplet}); err != nil {
+		return fmt.Errorf("error while writing images: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// stage identifies a single step in the provisioning process.
+type stage string
+
+const (
+	// stageRecoveryBoot SDP boots the device into the recovery image.
+	stageRecoveryBoot stage = "recovery_boot"
+	// stageFlash flashes the bootloader, OS, and (unless fusing) the applet.
+	stageFlash stage = "flash"
+	// stageWipe erases the applet data storage area, if requested.
+	stageWipe stage = "wipe"
+	// stageBootOS boots the device into the newly flashed OS and checks its status.
+	stageBootOS stage = "boot_os"
+	// stageFuse permanently fuses the device, if requested.
+	stageFuse stage = "fuse"
+	// stageFlashApplet flashes the applet onto a device which has just been fused.
+	stageFlashApplet stage = "flash_applet"
+	// stageStatus boots the device into the installed firmware, waits for it to report its
+	// witness identity, and runs a self-test.
+	stageStatus stage = "status"
+)
+
+// stages lists all provisioning stages in the order in which they must be performed.
+var stages = []stage{stageRecoveryBoot, stageFlash, stageWipe, stageBootOS, stageFuse, stageFlashApplet, stageStatus}
+
+// firmwareIndices holds the FT log indices of the firmware being installed on a device.
+type firmwareIndices struct {
+	Boot     uint64 `json:"boot"`
+	Recovery uint64 `json:"recovery"`
+	OS       uint64 `json:"os"`
+	Applet   uint64 `json:"applet"`
+}
+
+// journal records the progress made while provisioning a single device, so that an
+// interrupted provisioning run can be resumed from the point at which it stopped.
+//
+// The journal is held only in memory until the serial number of the device is known,
+// after which it's persisted to a file named after the serial number every time a
+// stage is completed.
+type journal struct {
+	// path is the location of the journal file, or empty if it's not yet known.
+	path string
+
+	// Serial is the serial number of the device being provisioned.
+	Serial string `json:"serial"`
+	// Completed is the most recent stage which has been successfully completed.
+	Completed stage `json:"completed"`
+	// Fuse records whether the device is to be fused during provisioning.
+	Fuse bool `json:"fuse"`
+	// Fused records whether the device has been successfully fused.
+	Fused bool `json:"fused"`
+	// FuseAuthorization is the signed fuse authorization which permitted the device to be fused.
+	FuseAuthorization string `json:"fuse_authorization,omitempty"`
+	// WitnessIdentity is the witness identity reported when the device first booted the
+	// newly installed applet, if it did so before provisioning completed.
+	WitnessIdentity string `json:"witness_identity,omitempty"`
+	// HABTarget is the release environment the firmware is targetting.
+	HABTarget string `json:"hab_target"`
+	// Firmware identifies the firmware being installed.
+	Firmware firmwareIndices `json:"firmware"`
+	// Updated is the time at which the journal was last updated.
+	Updated time.Time `json:"updated"`
+}
+
+// newJournal creates a new in-memory journal for provisioning the passed in firmware.
+func newJournal(fw *firmwares, fuse bool, habTarget string) *journal {
+	return &journal{
+		Fuse:      fuse,
+		HABTarget: habTarget,
+		Firmware: firmwareIndices{
+			Boot:     fw.bootloader.bundle.Index,
+			Recovery: fw.recovery.bundle.Index,
+			OS:       fw.trustedOS.bundle.Index,
+			Applet:   fw.trustedApplet.bundle.Index,
+		},
+	}
+}
+
+// journalPath returns the location of the journal file for the given device serial number.
+func journalPath(dir, serial string) string {
+	return filepath.Join(dir, serial+".json")
+}
+
+// readJournal reads the journal for the given device serial number from dir.
+func readJournal(dir, serial string) (*journal, error) {
+	p := journalPath(dir, serial)
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	j := &journal{}
+	if err := json.Unmarshal(b, j); err != nil {
+		return nil, fmt.Errorf("failed to parse journal %q: %v", p, err)
+	}
+	if j.Serial != serial {
+		return nil, fmt.Errorf("journal %q is for serial %q", p, j.Serial)
+	}
+	j.path = p
+	return j, nil
+}
+
+// compatible returns true if o was recording the provisioning of the same firmware
+// with the same settings as j.
+func (j *journal) compatible(o *journal) bool {
+	return j.Fuse == o.Fuse &&
+		j.HABTarget == o.HABTarget &&
+		j.Firmware.Boot == o.Firmware.Boot &&
+		j.Firmware.OS == o.Firmware.OS &&
+		j.Firmware.Applet == o.Firmware.Applet
+}
+
+// done returns true if all stages have been completed.
+func (j *journal) done() bool {
+	return j.Completed == stages[len(stages)-1]
+}
+
+// next returns the next stage to be performed.
+// It must not be called if the journal is done.
+func (j *journal) next() stage {
+	if j.Completed == "" {
+		return stages[0]
+	}
+	return stages[slices.Index(stages, j.Completed)+1]
+}
+
+// attach associates the journal with the device with the given serial number, and merges
+// in any progress recorded by a previous provisioning attempt of that device.
+//
+// Progress is only merged if no stage has been completed yet by this run, as any stages
+// which have been must be followed by all the ones after them.
+//
+// If dir is empty, the journal will not be persisted.
+func (j *journal) attach(dir, serial string) error {
+	if j.Serial != "" {
+		if j.Serial != serial {
+			return fmt.Errorf("device serial number %q does not match the device being provisioned (%q)", serial, j.Serial)
+		}
+		return nil
+	}
+	j.Serial = serial
+	if dir == "" {
+		return nil
+	}
+	j.path = journalPath(dir, serial)
+
+	o, err := readJournal(dir, serial)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		klog.Infof("Recording provisioning progress in %s", j.path)
+	case err != nil:
+		return err
+	default:
+		// Whatever else has happened, a device which was fused stays fused.
+		j.Fused = j.Fused || o.Fused
+		if j.FuseAuthorization == "" {
+			j.FuseAuthorization = o.FuseAuthorization
+		}
+		switch {
+		case o.done():
+			klog.Infof("Device %s was previously provisioned, starting again", serial)
+		case !j.compatible(o):
+			klog.Warningf("⚠️  Device %s has an unfinished provisioning attempt (completed stage %q) with different firmware or settings, starting again", serial, o.Completed)
+		case j.Completed != "":
+			// The device was identified late, and this run has already redone stages which
+			// the previous attempt may have got past, e.g. flashing over its applet. None
+			// of the stages after those can be skipped now.
+			klog.Infof("Device %s has an unfinished provisioning attempt (completed stage %q), but stages up to %q have been performed again, continuing from there", serial, o.Completed, j.Completed)
+		case slices.Index(stages, o.Completed) > slices.Index(stages, j.Completed):
+			klog.Infof("Device %s has an unfinished provisioning attempt, resuming after stage %q", serial, o.Completed)
+			j.Completed = o.Completed
+		}
+	}
+	return j.save()
+}
+
+// complete records that the stage s has been completed.
+//
+// Completing a stage never moves the journal backwards: attach may have merged in a previous
+// attempt's progress while s was being performed, in which case s was just the stage needed
+// to identify the device.
+func (j *journal) complete(s stage) error {
+	if slices.Index(stages, s) > slices.Index(stages, j.Completed) {
+		j.Completed = s
+	}
+	return j.save()
+}
+
+// save writes the journal to its file, if known.
+func (j *journal) save() error {
+	if j.path == "" {
+		return nil
+	}
+	j.Updated = time.Now().UTC()
+	b, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create journal directory: %v", err)
+	}
+	// Write to a temporary file and rename it into place so that a crash part way
+	// through can't leave a truncated journal behind.
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("failed to write journal: %v", err)
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		return fmt.Errorf("failed to update journal: %v", err)
+	}
+	return nil
+}
+
+// defaultJournalDir returns the directory in which journals are stored if the
+// --journal_dir flag is not set.
+func defaultJournalDir() string {
+	d, err := os.UserConfigDir()
+	if err != nil {
+		klog.Warningf("⚠️  Unable to determine config directory, provisioning progress will not be recorded: %v", err)
+		return ""
+	}
+	return filepath.Join(d, "armored-witness", "provision")
+}
@@ -0,0 +1,115 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestJournalResumeDuringStage(t *testing.T) {
+	const serial = "0123456789ABCDEF"
+	fw := firmwareIndices{Boot: 1, Recovery: 2, OS: 3, Applet: 4}
+	dir := t.TempDir()
+
+	// A previous attempt got as far as flashing the device.
+	prev := &journal{path: journalPath(dir, serial), Serial: serial, HABTarget: "ci", Firmware: fw}
+	if err := prev.complete(stageFlash); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	// The device is only identified part way through the recovery_boot stage.
+	j := &journal{HABTarget: "ci", Firmware: fw}
+	if got := j.next(); got != stageRecoveryBoot {
+		t.Fatalf("next() = %q, want %q", got, stageRecoveryBoot)
+	}
+	if err := j.attach(dir, serial); err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	if err := j.complete(stageRecoveryBoot); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if got, want := j.next(), stageWipe; got != want {
+		t.Errorf("next() = %q, want %q", got, want)
+	}
+
+	// The merged progress must also have been persisted.
+	r, err := readJournal(dir, serial)
+	if err != nil {
+		t.Fatalf("readJournal: %v", err)
+	}
+	if got, want := r.Completed, stageFlash; got != want {
+		t.Errorf("persisted Completed = %q, want %q", got, want)
+	}
+}
+
+func TestJournalCompleteIncompatible(t *testing.T) {
+	const serial = "0123456789ABCDEF"
+	dir := t.TempDir()
+
+	prev := &journal{path: journalPath(dir, serial), Serial: serial, HABTarget: "ci", Firmware: firmwareIndices{OS: 1}}
+	if err := prev.complete(stageBootOS); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	// Different firmware means starting again from scratch.
+	j := &journal{HABTarget: "ci", Firmware: firmwareIndices{OS: 2}}
+	if err := j.attach(dir, serial); err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	if err := j.complete(stageRecoveryBoot); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if got, want := j.next(), stageFlash; got != want {
+		t.Errorf("next() = %q, want %q", got, want)
+	}
+}
+
+func TestJournalLateAttach(t *testing.T) {
+	const serial = "0123456789ABCDEF"
+	fw := firmwareIndices{Boot: 1, Recovery: 2, OS: 3, Applet: 4}
+	dir := t.TempDir()
+
+	// A previous attempt got as far as flashing the applet onto the fused device.
+	prev := &journal{path: journalPath(dir, serial), Serial: serial, Fuse: true, HABTarget: "ci", Firmware: fw, Fused: true}
+	if err := prev.complete(stageFlashApplet); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	// The serial number couldn't be read in recovery mode, so the device is only identified
+	// once it boots the OS, by which time the applet has been flashed over again.
+	j := &journal{Fuse: true, HABTarget: "ci", Firmware: fw}
+	for _, s := range []stage{stageRecoveryBoot, stageFlash, stageWipe} {
+		if err := j.complete(s); err != nil {
+			t.Fatalf("complete: %v", err)
+		}
+	}
+	if err := j.attach(dir, serial); err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	if err := j.complete(stageBootOS); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if !j.Fused {
+		t.Error("Fused = false, want true")
+	}
+	for _, want := range []stage{stageFuse, stageFlashApplet, stageStatus} {
+		if got := j.next(); got != want {
+			t.Fatalf("next() = %q, want %q", got, want)
+		}
+		if err := j.complete(want); err != nil {
+			t.Fatalf("complete: %v", err)
+		}
+	}
+}
@@ -19,50 +19,31 @@ package main
 import (
 	"bytes"
 	"context"
-	"crypto/rand"
-	"crypto/rsa"
 	"encoding/gob"
 	"flag"
 	"fmt"
 	"net/url"
 	"os"
+	"os/signal"
 	"os/user"
 	"time"
 
-	"github.com/flynn/u2f/u2fhid"
 	"k8s.io/klog/v2"
 
 	"github.com/transparency-dev/armored-witness-boot/config"
 	"github.com/transparency-dev/armored-witness-common/release/firmware"
 	"github.com/transparency-dev/armored-witness-common/release/firmware/ftlog"
 	"github.com/transparency-dev/armored-witness-common/release/firmware/update"
-	"github.com/transparency-dev/armored-witness/internal/device"
 	"github.com/transparency-dev/armored-witness/internal/fetcher"
+	"github.com/transparency-dev/armored-witness/internal/layout"
+	"github.com/transparency-dev/armored-witness/internal/operator"
+	"github.com/transparency-dev/armored-witness/internal/pack"
 	"github.com/transparency-dev/armored-witness/internal/release"
 	"golang.org/x/exp/maps"
 	"golang.org/x/mod/sumdb/note"
 )
 
 const (
-	// Block size in bytes of the MMC device on the armored witness.
-	mmcBlockSize = 512
-
-	// bootloaderBlock defines the location of the first block of the bootloader on MMC.
-	bootloaderBlock = 0x2
-	// bootloaderConfigBlock defines the location of the bootloader config GOB on MMC.
-	// In constrast to the other firmware binaries below where each firmware is preceeded
-	// by its config GOB, the bootloader config is stored separatly due to the hard requirement
-	// for the binary location imposed by the i.MX ROM bootloader.
-	bootloaderConfigBlock = 0x4FB0
-	// osBlock defines the location of the first block of the TrustedOS on MMC.
-	osBlock = 0x5000
-	// appletBlock defines the location of the first block of the TrustedApplet on MMC.
-	appletBlock = 0x200000
-	// appletDataBlock defines the location of the applet data storage area.
-	appletDataBlock = 0x400000
-	// appletDataNumBlocks is the number of blocks in the applet data storage area.
-	appletDataNumBlocks = 0x400000
-
 	fuseWarning = `
 ████████████████████████████████████████████████████████████████████████████████
 
@@ -79,27 +60,18 @@ The use of this tool is therefore **at your own risk**.
 
 ████████████████████████████████████████████████████████████████████████████████
 `
-
-	operPlease = "🔷🔷🔷 🙋 OPERATOR: %s 🙏"
-)
-
-var (
-	// expectedSRKHashes maps known SRK hash values to the release environment they came from.
-	// These values MUST NOT be changed unless you really know what you're doing!
-	expectedSRKHashes = map[string]string{
-		// ci: From https://github.com/transparency-dev/armored-witness/blob/main/deployment/build_and_release/live/ci/terragrunt.hcl#L32
-		"b8ba457320663bf006accd3c57e06720e63b21ce5351cb91b4650690bb08d85a": "ci",
-		// prod: From https://github.com/transparency-dev/armored-witness/blob/main/deployment/build_and_release/live/prod/terragrunt.hcl#L33
-		"77e021cc51b5547fb0c2192fb32710bfa89b4bbaa7dab5f97fc585f673b0b236": "prod",
-	}
 )
 
 var (
-	template            = flag.String("template", "", fmt.Sprintf("One of the optional preconfigured templates (%v)", maps.Keys(release.Templates)))
+	template            = flag.String("template", "", fmt.Sprintf("One of the optional preconfigured templates (%v), or a template defined in --template_file.", maps.Keys(release.Templates)))
+	templateFile        = flag.String("template_file", "", "Path to a JSON file, or a directory of JSON files, defining additional templates. The files must be pinned with --template_sha256 or signed by --template_verifier.")
+	templateSHA256      = flag.String("template_sha256", "", "Comma separated list of hex encoded SHA256 hashes, one of which each --template_file must match.")
+	templateVerifier    = flag.String("template_verifier", "", "Verifier string for the key which each --template_file must be a signed note from.")
 	firmwareLogURL      = flag.String("firmware_log_url", "", "URL of the firmware transparency log to scan for firmware artefacts.")
 	firmwareLogOrigin   = flag.String("firmware_log_origin", "", "Origin string for the firmware transparency log.")
 	firmwareLogVerifier = flag.String("firmware_log_verifier", "", "Checkpoint verifier key for the firmware transparency log.")
 	binariesURL         = flag.String("binaries_url", "", "Base URL for fetching firmware artefacts referenced by FT log.")
+	firmwarePack        = flag.String("firmware_pack", "", "If set, firmware is taken from this firmware pack (see the export tool) rather than fetched from the FT log and binaries URL.")
 
 	appletVerifier   = flag.String("applet_verifier", "", "Verifier key for the applet manifest.")
 	bootVerifier     = flag.String("boot_verifier", "", "Verifier key for the boot manifest.")
@@ -107,33 +79,66 @@ var (
 	osVerifier2      = flag.String("os_verifier_2", "", "Verifier key 2 for the OS manifest.")
 	recoveryVerifier = flag.String("recovery_verifier", "", "Verifier key for the recovery manifest.")
 
-	osIndexOverride     = flag.Int64("os_index", -1, "Override the OS to install by specifying the index into the log for its manifest.")
-	appletIndexOverride = flag.Int64("applet_index", -1, "Override the Applet to install by specifying the index into the log for its manifest.")
+	osIndexOverride       = flag.Int64("os_index", -1, "Override the OS to install by specifying the index into the log for its manifest.")
+	appletIndexOverride   = flag.Int64("applet_index", -1, "Override the Applet to install by specifying the index into the log for its manifest.")
+	bootIndexOverride     = flag.Int64("boot_index", -1, "Override the Bootloader to install by specifying the index into the log for its manifest.")
+	recoveryIndexOverride = flag.Int64("recovery_index", -1, "Override the Recovery image to install by specifying the index into the log for its manifest.")
+	osVersion             = flag.String("os_version", "", "Install the highest OS release whose version matches this constraint, e.g. 0.4.x or <=1.2.0, rather than the latest.")
+	appletVersion         = flag.String("applet_version", "", "Install the highest Applet release whose version matches this constraint, e.g. 0.4.x or <=1.2.0, rather than the latest.")
+	osFile                = flag.String("os_file", "", "Install the OS from local files, given as BINARY,MANIFEST, where MANIFEST is the signed manifest exactly as it appears in the FT log.")
+	appletFile            = flag.String("applet_file", "", "Install the Applet from local files, given as BINARY,MANIFEST, where MANIFEST is the signed manifest exactly as it appears in the FT log.")
+	bootFile              = flag.String("boot_file", "", "Install the Bootloader from local files, given as BINARY,MANIFEST[,HAB_SIGNATURE], where MANIFEST is the signed manifest exactly as it appears in the FT log. If HAB_SIGNATURE is omitted, it's fetched from --binaries_url.")
+	recoveryFile          = flag.String("recovery_file", "", "Install the Recovery image from local files, given as BINARY,MANIFEST[,HAB_SIGNATURE], where MANIFEST is the signed manifest exactly as it appears in the FT log. If HAB_SIGNATURE is omitted, it's fetched from --binaries_url.")
+
+	witnessVerifiers = flag.String("witness_verifiers", "", "Comma separated list of verifier strings for witnesses, at least --witness_threshold of which must have cosigned the FT log checkpoints which firmware is verified against.")
+	witnessThreshold = flag.Int("witness_threshold", 0, "Number of --witness_verifiers which must have cosigned each FT log checkpoint.")
 
 	habTarget       = flag.String("hab_target", "", "Device type firmware must be targetting.")
+	habSRKHash      = flag.String("hab_srk_hash", "", "Hex encoded SRK hash of the HAB PKI for --hab_target. Required when fusing, devices reporting a different SRK hash will not be fused.")
 	blockDeviceGlob = flag.String("blockdevs", "/dev/disk/by-id/usb-F-Secure_USB_*0:0", "Glob for plausible block devices where the armored witness could appear.")
 
 	runAnyway   = flag.Bool("run_anyway", false, "Let the user override bailing on any potential problems we've detected.")
 	wipeWitness = flag.Bool("wipe_witness_state", false, "If true, erase the witness stored data.")
 
-	fuse = flag.Bool("fuse", false, "If set, device will be **permanently** fused to the release environment specified by --hab_target")
+	fuse              = flag.Bool("fuse", false, "If set, device will be **permanently** fused to the release environment specified by --hab_target")
+	fuseAuthorization = flag.String("fuse_authorization", "", "Path to a fuse authorization, signed by one of the fuse approvers listed in the --template, naming the devices which may be fused and the release environment they may be fused to. Required when fusing.")
+	fuseUnattended    = flag.Bool("fuse_unattended", false, "If set, the operator is not asked to confirm fusing each device by typing its serial number, and --fuse_authorization alone is relied upon.")
+
+	journalDir = flag.String("journal_dir", "", "Directory in which to record provisioning progress for each device. Defaults to a directory within the user's config directory.")
+	resume     = flag.String("resume", "", "Serial number of a device whose interrupted provisioning should be resumed from the last completed stage.")
+
+	recordSignerKey = flag.String("record_signer_key", "", "Path to a file containing the note signer key used to sign the provisioning record written for each provisioned device.")
+	recordDir       = flag.String("record_dir", "", "Directory in which to write provisioning records. Defaults to the journal directory.")
+
+	auditLogPath = flag.String("audit_log", "", "Path of the hash-chained audit log recording everything done to each device. Defaults to a file within the user's config directory, shared with the verify tool.")
+
+	operatorKind = flag.String("operator", "tty", fmt.Sprintf("How to interact with the operator, one of %v. The json operator writes events to stdout as JSON lines, and reads responses from stdin.", operator.Kinds))
+
+	benchMode = flag.Bool("bench", false, "If set, provision all devices which appear in SDP mode concurrently, pairing up each device's SDP, block, and witness USB devices by the USB port they're connected to. Runs until interrupted.")
+
+	upgrade        = flag.String("upgrade", "", fmt.Sprintf("If set, an already provisioned device is upgraded by reflashing only this comma separated list of components (%v), leaving all other data on the device untouched.", upgradeComponents))
+	allowDowngrade = flag.Bool("allow_downgrade", false, "If set, firmware which is older than the firmware already installed on the device will be installed anyway.")
+
+	allowHABTargetChange = flag.Bool("allow_hab_target_change", false, "If set, --upgrade=boot installs a bootloader for --hab_target even if the installed bootloader is for a different HAB target, or its HAB target can't be read. This will brick a device which is fused to the installed bootloader's HAB target.")
+
+	selfTestTimeout = flag.Duration("self_test_timeout", 5*time.Minute, "How long to wait for the witness applet to report its identity once provisioning is complete, before the self-test fails.")
+	selfTestOffline = flag.Bool("self_test_offline", false, "If set, the self-test run once provisioning is complete doesn't require the device to have a network link and IP address, e.g. when provisioning without network access.")
+
+	outputImage = flag.String("output_image", "", "If set, no device will be provisioned; instead a sparse MMC disk image containing the firmware will be written to this path.")
 )
 
+// templateSource returns where release templates are loaded from, according to flags.
+func templateSource() release.TemplateSource {
+	return release.TemplateSource{
+		Path:     *templateFile,
+		SHA256:   release.ParseHashes(*templateSHA256),
+		Verifier: *templateVerifier,
+	}
+}
+
 func applyFlagTemplate(k string) {
-	t, ok := release.Templates[k]
-	if !ok {
-		klog.Exitf("No such template %q", k)
-	}
-	for f, v := range t {
-		if u := flag.Lookup(f); u == nil {
-			klog.Exitf("Internal error - template flag --%v unknown", f)
-		} else if u.Value.String() != "" {
-			klog.Exitf("Cannot set --template and --%s", f)
-		}
-		klog.Infof("Using template flag setting --%v=%v", f, v)
-		if err := flag.Set(f, v); err != nil {
-			klog.Exitf("Failed to set template flag --%v: %v", f, err)
-		}
+	if err := templateSource().Apply(k, release.FlagSet(flag.CommandLine), nil); err != nil {
+		klog.Exitf("Failed to apply template %q: %v", k, err)
 	}
 }
 
@@ -145,21 +150,119 @@ func main() {
 	}
 	ctx := context.Background()
 
-	if u, err := user.Current(); err != nil {
-		klog.Exitf("Failed to determine who I'm running as: %v", err)
-	} else if u.Uid != "0" {
-		klog.Warningf("⚠️ This tool probably needs to be run as root (e.g. via sudo), it's running as %q (UID %q); re-run with the --run_anyway flag if you know better.", u.Username, u.Uid)
-		if !*runAnyway {
-			klog.Exit("Bailing.")
+	for c, fs := range overrideFlags() {
+		if len(fs) > 1 {
+			klog.Exitf("Only one of %v may be set to override the %s firmware.", fs, c)
 		}
 	}
+	if *fuse && *habSRKHash == "" {
+		klog.Exit("The --hab_srk_hash flag must be set when fusing.")
+	}
+	if *fuse && *fuseAuthorization == "" {
+		klog.Exit("The --fuse_authorization flag must be set when fusing, confirming the serial number interactively is not enough on its own.")
+	}
 
-	fw, err := fetchLatestArtefacts(ctx)
+	if *outputImage == "" {
+		if u, err := user.Current(); err != nil {
+			klog.Exitf("Failed to determine who I'm running as: %v", err)
+		} else if u.Uid != "0" {
+			klog.Warningf("⚠️ This tool probably needs to be run as root (e.g. via sudo), it's running as %q (UID %q); re-run with the --run_anyway flag if you know better.", u.Username, u.Uid)
+			if !*runAnyway {
+				klog.Exit("Bailing.")
+			}
+		}
+	}
+
+	var fw *firmwares
+	var err error
+	if *firmwarePack != "" {
+		fw, err = loadFirmwarePack(*firmwarePack)
+	} else {
+		fw, err = fetchLatestArtefacts(ctx)
+	}
 	if err != nil {
 		klog.Exitf("Failed to fetch latest firmware artefacts: %v", err)
 	}
+	if *habSRKHash != "" {
+		if err := checkSRKHash(fw, *habSRKHash); err != nil {
+			klog.Exitf("❌ Firmware does not match --hab_srk_hash: %v", err)
+		}
+	}
+
+	if *outputImage != "" {
+		if err := writeImage(*outputImage, fw); err != nil {
+			klog.Exitf("❌ Failed to write MMC image: %v", err)
+		}
+		klog.Infof("✅ MMC image written to %s", *outputImage)
+		return
+	}
 
-	if err := waitAndProvision(ctx, fw); err != nil {
+	if auditLog, err = openAuditLog(); err != nil {
+		klog.Exitf("Failed to open audit log: %v", err)
+	}
+	defer func() {
+		if err := auditLog.Close(); err != nil {
+			klog.Errorf("Failed to close audit log: %v", err)
+		}
+	}()
+
+	jDir := *journalDir
+	if jDir == "" {
+		jDir = defaultJournalDir()
+	}
+	op, err := operator.New(*operatorKind)
+	if err != nil {
+		klog.Exitf("Invalid --operator: %v", err)
+	}
+
+	if *upgrade != "" {
+		components, err := parseUpgradeComponents(*upgrade)
+		if err != nil {
+			klog.Exitf("Invalid --upgrade: %v", err)
+		}
+		if *fuse || *wipeWitness || *benchMode || *resume != "" {
+			klog.Exit("The --upgrade flag cannot be used with --fuse, --wipe_witness_state, --bench, or --resume.")
+		}
+		if err := runUpgrade(ctx, fw, op, components); err != nil {
+			klog.Exitf("❌ Failed to upgrade device: %v", err)
+		}
+		klog.Info("✅ Device upgraded!")
+		return
+	}
+
+	var rec *recorder
+	if *recordSignerKey != "" {
+		rDir := *recordDir
+		if rDir == "" {
+			rDir = jDir
+		}
+		if rec, err = newRecorder(*recordSignerKey, rDir); err != nil {
+			klog.Exitf("Failed to set up provisioning records: %v", err)
+		}
+	} else {
+		klog.Warning("⚠️  No --record_signer_key provided, provisioning records will not be written.")
+	}
+	if *fuse {
+		if fuseApprovers, err = fuseApproversFromTemplate(); err != nil {
+			klog.Exitf("Unable to fuse: %v", err)
+		}
+		if err := checkFuseAuthorization(rec); err != nil {
+			klog.Exitf("Invalid --fuse_authorization: %v", err)
+		}
+	}
+
+	if *benchMode {
+		if *resume != "" {
+			klog.Exit("The --resume and --bench flags cannot be used together; interrupted devices are resumed automatically in bench mode.")
+		}
+		ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
+		defer cancel()
+		if err := runBench(ctx, fw, jDir, rec, op); err != nil {
+			klog.Exitf("❌ %v", err)
+		}
+		return
+	}
+	if err := waitAndProvision(ctx, fw, jDir, rec, op, *resume); err != nil {
 		klog.Exitf("❌ Failed to provision device: %v", err)
 	}
 	klog.Info("✅ Device provisioned!")
@@ -264,10 +367,17 @@ func fetchLatestArtefacts(ctx context.Context) (*firmwares, error) {
 	if err != nil {
 		return nil, fmt.Errorf("updateFetcher.NewSession: %v", err)
 	}
+	pv, err := packVerifierFromFlags()
+	if err != nil {
+		return nil, err
+	}
 	bp := overridableBundleProvider{
 		delegate:     updateFetcher,
 		binFetcher:   binFetcher,
 		fetchSession: fetchSession,
+		logOrigin:    *firmwareLogOrigin,
+		logVerifier:  logVerifier,
+		verifier:     pv,
 	}
 
 	firmwares := &firmwares{}
@@ -279,7 +389,7 @@ func fetchLatestArtefacts(ctx context.Context) (*firmwares, error) {
 	klog.Infof("Found OS bundle @ %d", osFW.Index)
 	firmwares.trustedOS = &fw{
 		bundle: osFW,
-		block:  osBlock,
+		block:  layout.OS.Block,
 	}
 
 	appletFW, err := bp.GetApplet(ctx)
@@ -289,7 +399,7 @@ func fetchLatestArtefacts(ctx context.Context) (*firmwares, error) {
 	klog.Infof("Found Applet bundle @ %d", appletFW.Index)
 	firmwares.trustedApplet = &fw{
 		bundle: appletFW,
-		block:  appletBlock,
+		block:  layout.Applet.Block,
 	}
 
 	bootFW, err := bp.GetBoot(ctx)
@@ -299,8 +409,8 @@ func fetchLatestArtefacts(ctx context.Context) (*firmwares, error) {
 	klog.Infof("Found Bootloader bundle @ %d", bootFW.Index)
 	firmwares.bootloader = &fw{
 		bundle:      bootFW,
-		block:       bootloaderBlock,
-		configBlock: bootloaderConfigBlock,
+		block:       layout.Bootloader.Block,
+		configBlock: layout.BootloaderConfig.Block,
 	}
 
 	recoveryFW, err := bp.GetRecovery(ctx)
@@ -312,10 +422,35 @@ func fetchLatestArtefacts(ctx context.Context) (*firmwares, error) {
 		bundle: recoveryFW,
 	}
 
+	// The update fetcher trusts the log's own view of itself, so check that the checkpoints
+	// which will be installed along with the firmware were seen by enough witnesses too.
+	for _, f := range []*fw{firmwares.trustedOS, firmwares.trustedApplet, firmwares.bootloader, firmwares.recovery} {
+		if err := pv.WitnessPolicy.Check(f.bundle.Checkpoint); err != nil {
+			return nil, fmt.Errorf("bundle @ %d does not satisfy witness policy: %v", f.bundle.Index, err)
+		}
+	}
+	klog.Infof("✅ Firmware checkpoints satisfy witness policy: %s", pv.WitnessPolicy)
+
 	klog.Info("Loaded firmware artefacts.")
 	return firmwares, nil
 }
 
+// checkSRKHash checks that none of the firmware to be installed was built for an SRK hash
+// other than srkHash.
+func checkSRKHash(fws *firmwares, srkHash string) error {
+	for _, f := range []*fw{fws.bootloader, fws.recovery, fws.trustedOS, fws.trustedApplet} {
+		// The manifests have already been verified while fetching the firmware.
+		r, err := parseManifest(f.bundle.Manifest)
+		if err != nil {
+			return err
+		}
+		if err := release.CheckSRKHash(r, srkHash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func prepareFlashJobs(firmwares *firmwares) (*firmwareJobs, error) {
 	jobs := &firmwareJobs{}
 	if firmwares.trustedOS != nil {
@@ -334,7 +469,7 @@ func prepareFlashJobs(firmwares *firmwares) (*firmwareJobs, error) {
 		jobs.trustedApplet = flashJob{name: "applet", img: appletAndConfig, block: firmwares.trustedApplet.block}
 	}
 	if firmwares.bootloader != nil {
-		bootloaderConfig, err := configFromBundle(firmwares.bootloader.bundle, firmwares.bootloader.block*mmcBlockSize)
+		bootloaderConfig, err := configFromBundle(firmwares.bootloader.bundle, firmwares.bootloader.block*layout.BlockSize)
 		if err != nil {
 			return nil, fmt.Errorf("failed to prepare Bootloader config: %v", err)
 		}
@@ -344,184 +479,33 @@ func prepareFlashJobs(firmwares *firmwares) (*firmwareJobs, error) {
 		klog.Infof("Bootloader firmware is %d bytes + %d bytes HAB signature", len(firmwares.bootloader.bundle.Firmware), len(firmwares.bootloader.bundle.HABSignature))
 		jobs.bootloader = flashJob{name: "bootloader", img: bootloaderHAB, block: firmwares.bootloader.block}
 	}
+	// Catch oversized firmware now, rather than part way through provisioning a device.
+	for _, j := range []flashJob{jobs.trustedOS, jobs.trustedApplet, jobs.bootloaderConfig, jobs.bootloader} {
+		if j.img == nil {
+			continue
+		}
+		if err := j.check(); err != nil {
+			return nil, err
+		}
+	}
 	return jobs, nil
 }
 
 // waitAndProvision waits for a fresh armored witness device to be detected, and then provisions it.
-func waitAndProvision(ctx context.Context, fw *firmwares) error {
-	klog.Infof(operPlease, "please ensure boot switch is set to USB (towards RJ45 socket), and then connect unprovisioned device")
-
-	recoveryHAB := append(fw.recovery.bundle.Firmware, fw.recovery.bundle.HABSignature...)
-	klog.Infof("Recovery firmware is %d bytes + %d bytes HAB signature", len(fw.recovery.bundle.Firmware), len(fw.recovery.bundle.HABSignature))
-
-	// The device will initially be in HID mode (showing as "RecoveryMode" in the output to lsusb).
-	// So we'll detect it as such:
-	target, bDev, err := device.BootIntoRecovery(ctx, recoveryHAB, *blockDeviceGlob)
+//
+// Progress is recorded in a journal file named after the device's serial number, and if resumeSerial
+// is set provisioning of that device will resume from the last completed stage.
+func waitAndProvision(ctx context.Context, fw *firmwares, journalDir string, rec *recorder, op operator.Operator, resumeSerial string) error {
+	p, err := newProvisioner(fw, journalDir, rec, op)
 	if err != nil {
 		return err
 	}
-	klog.Infof("✅ Detected device %q", target.DeviceInfo.Path)
-	klog.Infof("✅ Detected blockdevice %v", bDev)
-
-	_, err = rsa.GenerateKey(rand.Reader, 4096)
-	if err != nil {
-		return fmt.Errorf("failed to generate ephemeral key: %v", err)
-	}
-
-	jobs, err := prepareFlashJobs(fw)
-	if err != nil {
-		return fmt.Errorf("failed to prepare flash jobs: %v", err)
-	}
-
-	flashStages := [][]flashJob{{jobs.trustedOS, jobs.bootloader, jobs.bootloaderConfig}}
-	if *fuse {
-		// If we need to fuse the device, we'll install the applet later on.
-		// This is ensure there's no unexpected CPU load on the device when
-		// we attempt to set fuses as this has been known to be timing sensitive.
-
-		// Add an extra job into the first stage to destroy any pre-existing applet installed on the device.
-		flashStages[0] = append(flashStages[0], flashJob{name: "DUMMY_APPLET", img: make([]byte, len(jobs.trustedApplet.img)), block: jobs.trustedApplet.block})
-		// Then create a second stage job to install the real applet
-		flashStages = append(flashStages, []flashJob{jobs.trustedApplet})
-	} else {
-		// We're not fusing, so can just install everything in the first stage.
-		flashStages[0] = append(flashStages[0], jobs.trustedApplet)
-
-	}
-	klog.Infof("Flashing images...")
-	if err := flashImages(bDev, flashStages[0]); err != nil {
-		return fmt.Errorf("error while flashing images: %v", err)
-	}
-	klog.Info("✅ Flashed images")
-
-	if *wipeWitness {
-		if err := wipeAppletData(bDev); err != nil {
-			return fmt.Errorf("error while wiping applet data: %v", err)
-		}
-	}
-
-	klog.Infof(operPlease, "please change boot switch to MMC (away from RJ45 socket), and then reboot device")
-	klog.Info("Waiting for device to boot...")
-
-	p, dev, err := waitForU2FDevice(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to find armored witness device: %v", err)
-	}
-
-	klog.Infof("✅ Detected device %q", p)
-	s, err := device.WitnessStatus(dev)
-	if err != nil {
-		return fmt.Errorf("failed to fetch witness status: %v", err)
-	}
-	klog.Infof("✅ Witness serial number %s found", s.Serial)
-	if s.HAB {
-		if *fuse && !*runAnyway {
-			return fmt.Errorf("witness serial number %s has HAB fuse set", s.Serial)
-		}
-		klog.Infof("⚠️  Witness serial number %s is already HAB fused", s.Serial)
-	} else {
-		klog.Infof("✅ Witness serial number %s is not HAB fused", s.Serial)
-	}
-
-	srkEnv, ok := expectedSRKHashes[s.SRKHash]
-	if !ok {
-		e := fmt.Errorf("witness OS reports UNKNOWN SRK Hash '%s', not fusing", s.SRKHash)
-		if *fuse {
-			return e
-		}
-		klog.Warningf("⚠️  %s", e.Error())
-	}
-	if srkEnv != *habTarget {
-		e := fmt.Errorf("witness OS reports SRK Hash (%s) for unexpected release environment %q - we're set to %q, not fusing", s.SRKHash, srkEnv, *habTarget)
-		if *fuse {
-			return e
-		}
-		klog.Warningf("⚠️  %s", e.Error())
-	}
-
-	if *fuse {
-		klog.Warningf("\n%s\n", fuseWarning)
-		for i := 5; i > 0; i-- {
-			klog.Infof(" Fusing in %d", i)
-			<-time.After(time.Second)
-		}
-		klog.Infof("Attempting to fuse device and activate HAB 🫣")
-		if err := device.ActivateHAB(dev); err != nil {
-			err = fmt.Errorf("device failed to activate HAB: %v", err)
-			if !*runAnyway {
-				return err
-			}
-			klog.Warningf("⚠️  %s, continuing anyway", err.Error())
-		}
-		klog.Info("✅ Fusing successful! 👌")
-		// Close dev as we'll need to re-open it below after the device has rebooted...
-		dev.Close()
-
-		klog.Infof("%d remaining firmware(s) to install", len(flashStages[1]))
-
-		klog.Infof(operPlease, "please change boot switch to USB (towards RJ45 socket), and then reboot device")
-		klog.Info("Waiting for device to boot...")
-		// The device will initially be in HID mode (showing as "RecoveryMode" in the output to lsusb).
-		// So we'll detect it as such:
-		target, bDev, err := device.BootIntoRecovery(ctx, recoveryHAB, *blockDeviceGlob)
-		if err != nil {
+	if resumeSerial != "" {
+		if err := p.resume(resumeSerial); err != nil {
 			return err
 		}
-		klog.Infof("✅ Detected device %q", target.DeviceInfo.Path)
-		klog.Infof("✅ Detected blockdevice %v", bDev)
-
-		klog.Infof("Flashing Applet image...")
-		if err := flashImages(bDev, flashStages[1]); err != nil {
-			return fmt.Errorf("error while flashing Applet image: %v", err)
-		}
-		klog.Info("✅ Flashed Applet image")
-
-		klog.Infof(operPlease, "please change boot switch to MMC (away from RJ45 socket), and then reboot device")
-		klog.Info("Waiting for device to boot...")
-		p, dev, err = waitForU2FDevice(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to find armored witness device: %v", err)
-		}
-
-		klog.Infof("✅ Detected device %q", p)
-		s, err = device.WitnessStatus(dev)
-		if err != nil {
-			return fmt.Errorf("failed to fetch witness status: %v", err)
-		}
-	}
-
-	klog.Infof(operPlease, "please reboot device")
-	klog.Info("Waiting for device to boot...")
-
-	klog.Infof("✅ Witness ID %s provisioned", s.Witness.Identity)
-
-	dev.Close()
-
-	return nil
-
-}
-
-// waitForU2FDevice waits for a device running armored witness firmware
-// to appear on the USB bus.
-// Returns the device path & opened device.
-func waitForU2FDevice(ctx context.Context) (string, *u2fhid.Device, error) {
-	klog.Info("Waiting for armored witness device to be detected...")
-	for {
-		select {
-		case <-ctx.Done():
-			return "", nil, ctx.Err()
-		case <-time.After(time.Second):
-			p, target, err := device.DetectU2F()
-			if err != nil {
-				klog.Warningf("Failed to detect devices: %v", err)
-				continue
-			}
-			if target == nil {
-				continue
-			}
-			return p, target, nil
-		}
 	}
+	return p.run(ctx)
 }
 
 type flashJob struct {
@@ -530,13 +514,34 @@ type flashJob struct {
 	block int64
 }
 
-// flashImages writes all the images in fw to the specified block device.
+// check returns an error if the image would not fit in the MMC region it's to be written to.
+func (j flashJob) check() error {
+	if err := layout.CheckWrite(j.block, int64(len(j.img))); err != nil {
+		return fmt.Errorf("%s: %v", j.name, err)
+	}
+	return nil
+}
+
+// flashImages writes all the images in fw to the specified block device or image file,
+// and then reads them back to check that they were written correctly.
 func flashImages(dev string, jobs []flashJob) error {
-	for i := 5; i > 0; i-- {
-		klog.Infof("  Flashing in %d", i)
-		<-time.After(time.Second)
+	if err := writeFlashJobs(dev, jobs); err != nil {
+		return err
+	}
+	klog.Info("Reading back flashed images...")
+	if err := verifyFlashJobs(dev, jobs); err != nil {
+		return fmt.Errorf("read-back verification failed: %v", err)
 	}
+	return nil
+}
 
+// writeFlashJobs writes all the images in jobs to the specified block device or image file.
+func writeFlashJobs(dev string, jobs []flashJob) error {
+	for _, p := range jobs {
+		if err := p.check(); err != nil {
+			return err
+		}
+	}
 	f, err := os.OpenFile(dev, os.O_RDWR|os.O_SYNC, 0o600)
 	if err != nil {
 		return fmt.Errorf("error opening %v: %v", dev, err)
@@ -559,7 +564,7 @@ func flashImages(dev string, jobs []flashJob) error {
 
 // flashImage writes the image to the file starting at the specified block.
 func flashImage(image []byte, to *os.File, atBlock int64) error {
-	offset := atBlock * mmcBlockSize
+	offset := atBlock * layout.BlockSize
 	if n, err := to.WriteAt(image, offset); err != nil {
 		return err
 	} else if l := len(image); n != l {
@@ -572,11 +577,14 @@ func flashImage(image []byte, to *os.File, atBlock int64) error {
 // block is the first MMC block of the config+ELF region.
 func prepareELF(bundle firmware.Bundle, block int64) ([]byte, error) {
 	// For ELF firmwares (OS & Applet), the on-MMC layout is [configGOB|padding|ELF]
-	fwOffset := block*mmcBlockSize + config.MaxLength
+	fwOffset := block*layout.BlockSize + config.MaxLength
 	cfgGob, err := configFromBundle(bundle, fwOffset)
 	if err != nil {
 		return nil, err
 	}
+	if l := int64(len(cfgGob)); l > config.MaxLength {
+		return nil, fmt.Errorf("config is %d bytes, larger than the maximum of %d", l, config.MaxLength)
+	}
 
 	buf := bytes.Buffer{}
 	buf.Write(cfgGob)
@@ -621,22 +629,23 @@ func wipeAppletData(dev string) error {
 		}
 	}()
 
-	klog.Infof("Wiping data area blocks [0x%x, 0x%x)...", appletDataBlock, appletDataBlock+appletDataNumBlocks)
-	chunkBlocks := 2048
-	empty := make([]byte, chunkBlocks*mmcBlockSize)
+	r := layout.AppletData
+	klog.Infof("Wiping data area blocks [0x%x, 0x%x)...", r.Block, r.End())
+	chunkBlocks := int64(2048)
+	empty := make([]byte, chunkBlocks*layout.BlockSize)
 	t := time.NewTicker(5 * time.Second)
 	defer t.Stop()
-	for i := 0; i < appletDataNumBlocks; i += chunkBlocks {
+	for i := int64(0); i < r.NumBlocks; i += chunkBlocks {
 		select {
 		case <-t.C:
-			klog.Infof("   %3d%%", (i*100)/appletDataNumBlocks)
+			klog.Infof("   %3d%%", (i*100)/r.NumBlocks)
 		default:
 		}
 
-		offset := (int64(appletDataBlock+i) * mmcBlockSize)
-		if appletDataNumBlocks-i < chunkBlocks {
-			chunkBlocks = appletDataNumBlocks - i
-			empty = empty[:chunkBlocks*mmcBlockSize]
+		offset := (r.Block + i) * layout.BlockSize
+		if r.NumBlocks-i < chunkBlocks {
+			chunkBlocks = r.NumBlocks - i
+			empty = empty[:chunkBlocks*layout.BlockSize]
 		}
 		if _, err := f.WriteAt(empty, offset); err != nil {
 			return fmt.Errorf("WriteAt: %v", err)
@@ -646,61 +655,83 @@ func wipeAppletData(dev string) error {
 	return nil
 }
 
+// overridableBundleProvider provides the latest firmware from the FT log, unless
+// overridden by flags selecting a particular release.
 type overridableBundleProvider struct {
 	delegate bundleProvider
 
 	fetchSession update.FetchSession
 	binFetcher   update.BinaryFetcher
+	logOrigin    string
+	logVerifier  note.Verifier
+	// verifier checks firmware taken from local files.
+	verifier *pack.Verifier
+
+	// releases holds every release in the log, once it's been scanned.
+	releases []fetcher.Release
 }
 
 func (p *overridableBundleProvider) GetOS(ctx context.Context) (firmware.Bundle, error) {
-	if *osIndexOverride < 0 {
-		return p.delegate.GetOS(ctx)
-	}
+	switch {
+	case *osIndexOverride >= 0:
+		return p.fetchIndex(ctx, ftlog.ComponentOS, uint64(*osIndexOverride))
+	case *osVersion != "":
+		return p.fetchVersion(ctx, ftlog.ComponentOS, *osVersion)
+	case *osFile != "":
+		return p.fetchLocal(ctx, ftlog.ComponentOS, *osFile)
+	}
+	return p.delegate.GetOS(ctx)
+}
 
-	i := uint64(*osIndexOverride)
+func (p *overridableBundleProvider) GetApplet(ctx context.Context) (firmware.Bundle, error) {
+	switch {
+	case *appletIndexOverride >= 0:
+		return p.fetchIndex(ctx, ftlog.ComponentApplet, uint64(*appletIndexOverride))
+	case *appletVersion != "":
+		return p.fetchVersion(ctx, ftlog.ComponentApplet, *appletVersion)
+	case *appletFile != "":
+		return p.fetchLocal(ctx, ftlog.ComponentApplet, *appletFile)
+	}
+	return p.delegate.GetApplet(ctx)
+}
 
-	bundle, release, err := p.fetchSession.Fetch(ctx, i)
-	if err != nil {
-		return firmware.Bundle{}, err
-	}
-	if release.Component != ftlog.ComponentOS {
-		return firmware.Bundle{}, fmt.Errorf("overridden OS index %d is of type %s, not required type %s", i, release.Component, ftlog.ComponentOS)
-	}
-	bundle.Firmware, _, err = p.binFetcher(ctx, *release)
-	if err != nil {
-		return firmware.Bundle{}, fmt.Errorf("binFetcher(): %v", err)
+func (p *overridableBundleProvider) GetRecovery(ctx context.Context) (firmware.Bundle, error) {
+	switch {
+	case *recoveryIndexOverride >= 0:
+		return p.fetchIndex(ctx, ftlog.ComponentRecovery, uint64(*recoveryIndexOverride))
+	case *recoveryFile != "":
+		return p.fetchLocal(ctx, ftlog.ComponentRecovery, *recoveryFile)
 	}
-
-	return *bundle, nil
+	return p.delegate.GetRecovery(ctx)
 }
 
-func (p *overridableBundleProvider) GetApplet(ctx context.Context) (firmware.Bundle, error) {
-	if *appletIndexOverride < 0 {
-		return p.delegate.GetApplet(ctx)
+func (p *overridableBundleProvider) GetBoot(ctx context.Context) (firmware.Bundle, error) {
+	switch {
+	case *bootIndexOverride >= 0:
+		return p.fetchIndex(ctx, ftlog.ComponentBoot, uint64(*bootIndexOverride))
+	case *bootFile != "":
+		return p.fetchLocal(ctx, ftlog.ComponentBoot, *bootFile)
 	}
+	return p.delegate.GetBoot(ctx)
+}
 
-	i := uint64(*appletIndexOverride)
-
+// fetchIndex fetches the bundle for the release at index i in the log, which must be a
+// release of the given component.
+func (p *overridableBundleProvider) fetchIndex(ctx context.Context, component string, i uint64) (firmware.Bundle, error) {
 	bundle, release, err := p.fetchSession.Fetch(ctx, i)
 	if err != nil {
 		return firmware.Bundle{}, err
 	}
-	if release.Component != ftlog.ComponentApplet {
-		return firmware.Bundle{}, fmt.Errorf("overridden Applet index %d is of type %s, not required type %s", i, release.Component, ftlog.ComponentApplet)
+	if release.Component != component {
+		return firmware.Bundle{}, fmt.Errorf("overridden %s index %d is of type %s, not required type %s", component, i, release.Component, component)
+	}
+	if isHABComponent(component) && *habTarget != "" && (release.HAB == nil || release.HAB.Target != *habTarget) {
+		return firmware.Bundle{}, fmt.Errorf("overridden %s index %d is not for HAB target %q", component, i, *habTarget)
 	}
-	bundle.Firmware, _, err = p.binFetcher(ctx, *release)
+	bundle.Firmware, bundle.HABSignature, err = p.binFetcher(ctx, *release)
 	if err != nil {
 		return firmware.Bundle{}, fmt.Errorf("binFetcher(): %v", err)
 	}
 
 	return *bundle, nil
 }
-
-func (p *overridableBundleProvider) GetRecovery(ctx context.Context) (firmware.Bundle, error) {
-	return p.delegate.GetRecovery(ctx)
-}
-
-func (p *overridableBundleProvider) GetBoot(ctx context.Context) (firmware.Bundle, error) {
-	return p.delegate.GetBoot(ctx)
-}
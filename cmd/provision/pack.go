@@ -0,0 +1,88 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"k8s.io/klog/v2"
+
+	"github.com/transparency-dev/armored-witness/internal/layout"
+	"github.com/transparency-dev/armored-witness/internal/pack"
+	"github.com/transparency-dev/armored-witness/internal/policy"
+	"github.com/transparency-dev/armored-witness/internal/release"
+)
+
+// loadFirmwarePack reads the firmware pack at the given path, and verifies its contents
+// using the log and manifest verifiers passed in through flags.
+func loadFirmwarePack(path string) (*firmwares, error) {
+	for _, fs := range overrideFlags() {
+		if len(fs) > 0 {
+			return nil, fmt.Errorf("%v cannot be used with a firmware pack", fs)
+		}
+	}
+	pv, err := packVerifierFromFlags()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open firmware pack: %v", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			klog.Errorf("Error closing %v: %v", path, err)
+		}
+	}()
+	p, err := pack.Read(f)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := pv.Verify(p); err != nil {
+		return nil, fmt.Errorf("firmware pack failed verification: %v", err)
+	}
+	klog.Infof("✅ Firmware pack verified: Bootloader @ %d, Recovery @ %d, OS @ %d, Applet @ %d", p.Boot.Index, p.Recovery.Index, p.OS.Index, p.Applet.Index)
+
+	return &firmwares{
+		bootloader: &fw{
+			bundle:      p.Boot,
+			block:       layout.Bootloader.Block,
+			configBlock: layout.BootloaderConfig.Block,
+		},
+		recovery: &fw{
+			bundle: p.Recovery,
+		},
+		trustedOS: &fw{
+			bundle: p.OS,
+			block:  layout.OS.Block,
+		},
+		trustedApplet: &fw{
+			bundle: p.Applet,
+			block:  layout.Applet.Block,
+		},
+	}, nil
+}
+
+// packVerifierFromFlags creates a firmware pack verifier from information passed in through flags.
+func packVerifierFromFlags() (*pack.Verifier, error) {
+	wp, err := policy.FromFlags(*witnessVerifiers, *witnessThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("invalid witness policy: %v", err)
+	}
+	return pack.VerifierFromFlags(release.FlagSet(flag.CommandLine), wp)
+}
@@ -0,0 +1,111 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+
+	"k8s.io/klog/v2"
+
+	"github.com/transparency-dev/armored-witness/internal/layout"
+)
+
+const (
+	// directIOAlignment is the alignment required for buffers used to read from
+	// files opened for direct (uncached) I/O.
+	directIOAlignment = 4096
+	// readBackChunkSize is the number of bytes read from the device at a time
+	// when verifying flashed images.
+	readBackChunkSize = 1 << 20
+)
+
+// verifyFlashJobs reads back the region of dev written by each of the jobs, and checks
+// that its SHA256 hash matches that of the image which was written.
+//
+// The device is re-opened bypassing the host's page cache where the platform supports it,
+// so that the data is actually read back from the device rather than from memory.
+//
+// An error is returned if any region does not match.
+func verifyFlashJobs(dev string, jobs []flashJob) error {
+	f, err := openUncached(dev)
+	if err != nil {
+		return fmt.Errorf("error opening %v: %v", dev, err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			klog.Errorf("Error closing %v: %v", dev, err)
+		}
+	}()
+
+	errs := []error{}
+	for _, p := range jobs {
+		want := sha256.Sum256(p.img)
+		got, err := hashRegion(f, p.block*layout.BlockSize, int64(len(p.img)))
+		if err != nil {
+			klog.Infof("  ❌ %s @ 0x%0x: %v", p.name, p.block, err)
+			errs = append(errs, fmt.Errorf("failed to read back %s: %v", p.name, err))
+			continue
+		}
+		if got != want {
+			klog.Infof("  ❌ %s @ 0x%0x: read back hash %x, expected %x", p.name, p.block, got, want)
+			errs = append(errs, fmt.Errorf("%s read back hash %x does not match flashed image hash %x", p.name, got, want))
+			continue
+		}
+		klog.Infof("  ✅ %s @ 0x%0x: read back hash %x", p.name, p.block, got)
+	}
+	return errors.Join(errs...)
+}
+
+// hashRegion returns the SHA256 hash of the length bytes found at offset in f.
+//
+// Reads are made in whole MMC blocks using an aligned buffer, so this is safe to use
+// with files opened for direct I/O.
+func hashRegion(f *os.File, offset int64, length int64) ([sha256.Size]byte, error) {
+	h := sha256.New()
+	buf := alignedBuffer(readBackChunkSize)
+	for remaining := length; remaining > 0; {
+		c := min(int64(len(buf)), remaining)
+		// Reads must cover a whole number of blocks.
+		n := (c + layout.BlockSize - 1) / layout.BlockSize * layout.BlockSize
+		if r, err := f.ReadAt(buf[:n], offset); int64(r) < c {
+			if err == nil || errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return [sha256.Size]byte{}, fmt.Errorf("short read at offset 0x%x: %v", offset, err)
+		}
+		h.Write(buf[:c])
+		offset += c
+		remaining -= c
+	}
+	var ret [sha256.Size]byte
+	copy(ret[:], h.Sum(nil))
+	return ret, nil
+}
+
+// alignedBuffer returns a slice of the given size whose backing memory is aligned
+// to directIOAlignment.
+func alignedBuffer(size int) []byte {
+	b := make([]byte, size+directIOAlignment)
+	off := 0
+	if r := int(uintptr(unsafe.Pointer(&b[0])) & (directIOAlignment - 1)); r != 0 {
+		off = directIOAlignment - r
+	}
+	return b[off : off+size]
+}
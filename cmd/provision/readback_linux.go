@@ -0,0 +1,39 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+
+	"k8s.io/klog/v2"
+)
+
+// openUncached opens the named file for reading with O_DIRECT, so that reads bypass
+// the page cache.
+//
+// Some filesystems (e.g. tmpfs) do not support O_DIRECT, in which case the file is
+// opened normally instead.
+func openUncached(name string) (*os.File, error) {
+	f, err := os.OpenFile(name, os.O_RDONLY|syscall.O_DIRECT, 0)
+	if errors.Is(err, syscall.EINVAL) {
+		klog.Warningf("⚠️  %s does not support direct I/O, reading back via page cache", name)
+		return os.Open(name)
+	}
+	return f, err
+}
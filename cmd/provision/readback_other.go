@@ -0,0 +1,32 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+
+package main
+
+import (
+	"os"
+
+	"k8s.io/klog/v2"
+)
+
+// openUncached opens the named file for reading.
+//
+// Direct I/O is not supported on this platform, so reads may be served from the
+// page cache.
+func openUncached(name string) (*os.File, error) {
+	klog.Warningf("⚠️  Direct I/O is not supported on this platform, reading back %s via page cache", name)
+	return os.Open(name)
+}
@@ -0,0 +1,95 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/transparency-dev/armored-witness/internal/record"
+	"golang.org/x/mod/sumdb/note"
+)
+
+// recorder writes signed provisioning records for devices which have been provisioned.
+type recorder struct {
+	signer note.Signer
+	dir    string
+}
+
+// newRecorder creates a recorder which signs records with the note signer key stored in
+// keyFile, and writes them to dir.
+func newRecorder(keyFile, dir string) (*recorder, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("no directory to write provisioning records to")
+	}
+	k, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read record signer key: %v", err)
+	}
+	s, err := note.NewSigner(strings.TrimSpace(string(k)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid record signer key: %v", err)
+	}
+	return &recorder{signer: s, dir: dir}, nil
+}
+
+// write signs the record and stores it in a file named after the device serial number,
+// returning the path to the file.
+func (r *recorder) write(rec *record.Record) (string, error) {
+	b, err := record.Sign(rec, r.signer)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign record: %v", err)
+	}
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create record directory: %v", err)
+	}
+	p := filepath.Join(r.dir, rec.Serial+".record")
+	if err := os.WriteFile(p, b, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write record: %v", err)
+	}
+	return p, nil
+}
+
+// newRecord creates a provisioning record for the device being provisioned by p, using
+// the most recent status it reported.
+func (p *provisioner) newRecord() (*record.Record, error) {
+	s := p.status
+	w := s.GetWitness()
+	if w == nil {
+		return nil, fmt.Errorf("device %s did not report its witness status", s.Serial)
+	}
+	return &record.Record{
+		Serial:            s.Serial,
+		SRKHash:           s.SRKHash,
+		HAB:               s.HAB,
+		HABTarget:         p.journal.HABTarget,
+		FuseAuthorization: p.journal.FuseAuthorization,
+		WitnessIdentity:   w.Identity,
+		IDAttestPublicKey: w.IDAttestPublicKey,
+		AttestedID:        w.AttestedID,
+		AttestedBastionID: w.AttestedBastionID,
+		Firmware: record.Indices{
+			Boot:     p.journal.Firmware.Boot,
+			Recovery: p.journal.Firmware.Recovery,
+			OS:       p.journal.Firmware.OS,
+			Applet:   p.journal.Firmware.Applet,
+		},
+		Checkpoint: string(p.fw.trustedOS.bundle.Checkpoint),
+		Time:       time.Now().UTC(),
+	}, nil
+}
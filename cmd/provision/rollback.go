@@ -0,0 +1,111 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+)
+
+// rollbackRow describes the comparison between the installed and new firmware for a
+// single component.
+type rollbackRow struct {
+	name      string
+	installed string
+	new       string
+	status    string
+	// err is set if installing the new firmware must not go ahead.
+	err error
+}
+
+// checkRollback compares the firmware currently installed on the MMC at bDev with the
+// firmware in targets.
+//
+// It returns a table summarising the comparison, which should be shown to the operator,
+// and an error if any of the targets would be downgraded and --allow_downgrade is not set.
+//
+// A component is considered to be downgraded if the new firmware has a lower semantic
+// version, or the same version but an earlier FT log index, than the installed firmware.
+// Components whose installed version can't be determined, e.g. because the device has
+// never been provisioned, are not checked.
+func checkRollback(bDev string, targets []upgradeTarget) (string, error) {
+	rows := []rollbackRow{}
+	errs := []error{}
+	for _, t := range targets {
+		r := compareInstalled(bDev, t)
+		if r.err != nil {
+			errs = append(errs, r.err)
+		}
+		rows = append(rows, r)
+	}
+
+	b := &bytes.Buffer{}
+	w := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COMPONENT\tINSTALLED\tNEW\tSTATUS")
+	for _, r := range rows {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.name, r.installed, r.new, r.status)
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	return b.String(), errors.Join(errs...)
+}
+
+// compareInstalled compares the firmware installed on bDev for a single target with
+// the firmware which is to be installed.
+func compareInstalled(bDev string, t upgradeTarget) rollbackRow {
+	r := rollbackRow{name: t.name, installed: "-"}
+	want, err := parseManifest(t.fw.bundle.Manifest)
+	if err != nil {
+		r.new, r.status = "-", "invalid"
+		r.err = fmt.Errorf("%s: failed to parse manifest to be installed: %v", t.name, err)
+		return r
+	}
+	r.new = fmt.Sprintf("%s @ %d", want.Git.TagName, t.fw.bundle.Index)
+
+	got, gotIndex, err := installedRelease(bDev, t.cfgBlock)
+	if err != nil {
+		// There's nothing to protect if we can't tell what's installed, e.g. because this
+		// is a fresh device or a previous install was interrupted.
+		r.status = "unknown"
+		return r
+	}
+	r.installed = fmt.Sprintf("%s @ %d", got.Git.TagName, gotIndex)
+	if got.Component != want.Component {
+		r.status = "wrong component"
+		r.err = fmt.Errorf("%s: installed firmware is for component %q, not %q", t.name, got.Component, want.Component)
+		return r
+	}
+
+	c := want.Git.TagName.Compare(got.Git.TagName)
+	if c == 0 {
+		c = cmp.Compare(t.fw.bundle.Index, gotIndex)
+	}
+	switch {
+	case c > 0:
+		r.status = "upgrade"
+	case c == 0:
+		r.status = "reinstall"
+	case *allowDowngrade:
+		r.status = "DOWNGRADE (allowed)"
+	default:
+		r.status = "DOWNGRADE"
+		r.err = fmt.Errorf("%s: installed firmware %s is newer than %s, use --allow_downgrade to install it anyway", t.name, r.installed, r.new)
+	}
+	return r
+}
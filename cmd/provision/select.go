@@ -0,0 +1,156 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/transparency-dev/armored-witness-common/release/firmware"
+	"github.com/transparency-dev/armored-witness-common/release/firmware/ftlog"
+	"github.com/transparency-dev/armored-witness/internal/fetcher"
+	"github.com/transparency-dev/armored-witness/internal/release"
+	"k8s.io/klog/v2"
+)
+
+// fetchVersion fetches the bundle for the highest release of component in the log whose
+// version matches the constraint c.
+//
+// As with the latest release, if there are several releases with the same version, the
+// one which was logged last is chosen.
+func (p *overridableBundleProvider) fetchVersion(ctx context.Context, component string, c string) (firmware.Bundle, error) {
+	con, err := release.ParseConstraint(c)
+	if err != nil {
+		return firmware.Bundle{}, err
+	}
+	rs, err := p.scanLog(ctx)
+	if err != nil {
+		return firmware.Bundle{}, err
+	}
+
+	total, matches := 0, []fetcher.Release{}
+	for _, r := range rs {
+		if r.Release.Component != component {
+			continue
+		}
+		total++
+		if !con.Matches(r.Release.Git.TagName) {
+			continue
+		}
+		klog.V(1).Infof("%s release %s matches version constraint %q", component, r, c)
+		matches = append(matches, r)
+	}
+	chosen := fetcher.Latest(matches, component, "")
+	if chosen == nil {
+		return firmware.Bundle{}, fmt.Errorf("none of the %d %s releases in the log match version constraint %q", total, component, c)
+	}
+	klog.Infof("Version constraint %q matches %d of %d %s releases in the log; chose the highest, %s (latest release is %s)", c, len(matches), total, component, chosen, fetcher.Latest(rs, component, ""))
+	return p.fetchIndex(ctx, component, chosen.Index)
+}
+
+// scanLog returns every firmware release in the log, in log order.
+//
+// The whole log is only scanned once, and the releases are reused by later calls.
+func (p *overridableBundleProvider) scanLog(ctx context.Context) ([]fetcher.Release, error) {
+	if p.releases != nil {
+		return p.releases, nil
+	}
+	rs, _, err := fetcher.Scan(ctx, p.fetchSession, p.logOrigin, p.logVerifier)
+	if err != nil {
+		return nil, err
+	}
+	p.releases = rs
+	return rs, nil
+}
+
+// fetchLocal reads the bundle for component from local files, given as a comma separated
+// list of the paths to the firmware binary, its signed manifest, and optionally its HAB
+// signature.
+//
+// The manifest must be in the FT log, and the bundle must pass the same checks as firmware
+// taken from a firmware pack.
+func (p *overridableBundleProvider) fetchLocal(ctx context.Context, component string, files string) (firmware.Bundle, error) {
+	paths := strings.Split(files, ",")
+	if len(paths) < 2 || len(paths) > 3 || (len(paths) == 3 && !isHABComponent(component)) {
+		return firmware.Bundle{}, fmt.Errorf("invalid %s files %q", component, files)
+	}
+	bin, err := os.ReadFile(paths[0])
+	if err != nil {
+		return firmware.Bundle{}, fmt.Errorf("failed to read %s binary: %v", component, err)
+	}
+	m, err := os.ReadFile(paths[1])
+	if err != nil {
+		return firmware.Bundle{}, fmt.Errorf("failed to read %s manifest: %v", component, err)
+	}
+
+	rs, err := p.scanLog(ctx)
+	if err != nil {
+		return firmware.Bundle{}, err
+	}
+	i := slices.IndexFunc(rs, func(r fetcher.Release) bool { return bytes.Equal(r.Manifest, m) })
+	if i < 0 {
+		return firmware.Bundle{}, fmt.Errorf("%s manifest %s is not in the FT log", component, paths[1])
+	}
+	bundle, r, err := p.fetchSession.Fetch(ctx, rs[i].Index)
+	if err != nil {
+		return firmware.Bundle{}, err
+	}
+	bundle.Firmware = bin
+	switch {
+	case len(paths) == 3:
+		if bundle.HABSignature, err = os.ReadFile(paths[2]); err != nil {
+			return firmware.Bundle{}, fmt.Errorf("failed to read %s HAB signature: %v", component, err)
+		}
+	case r.HAB != nil && len(r.HAB.SignatureDigestSha256) > 0:
+		if _, bundle.HABSignature, err = p.binFetcher(ctx, *r); err != nil {
+			return firmware.Bundle{}, fmt.Errorf("binFetcher(): %v", err)
+		}
+	}
+	if _, err := p.verifier.VerifyBundle(component, *bundle); err != nil {
+		return firmware.Bundle{}, fmt.Errorf("local %s firmware failed verification: %v", component, err)
+	}
+	klog.Infof("Using local %s firmware %s, logged at index %d", component, paths[0], bundle.Index)
+	return *bundle, nil
+}
+
+// overrideFlags returns the names of the flags which are set to override the latest
+// firmware, keyed by component.
+func overrideFlags() map[string][]string {
+	r := map[string][]string{}
+	add := func(c string, set bool, name string) {
+		if set {
+			r[c] = append(r[c], "--"+name)
+		}
+	}
+	add(ftlog.ComponentOS, *osIndexOverride >= 0, "os_index")
+	add(ftlog.ComponentOS, *osVersion != "", "os_version")
+	add(ftlog.ComponentOS, *osFile != "", "os_file")
+	add(ftlog.ComponentApplet, *appletIndexOverride >= 0, "applet_index")
+	add(ftlog.ComponentApplet, *appletVersion != "", "applet_version")
+	add(ftlog.ComponentApplet, *appletFile != "", "applet_file")
+	add(ftlog.ComponentBoot, *bootIndexOverride >= 0, "boot_index")
+	add(ftlog.ComponentBoot, *bootFile != "", "boot_file")
+	add(ftlog.ComponentRecovery, *recoveryIndexOverride >= 0, "recovery_index")
+	add(ftlog.ComponentRecovery, *recoveryFile != "", "recovery_file")
+	return r
+}
+
+func isHABComponent(c string) bool {
+	return c == ftlog.ComponentBoot || c == ftlog.ComponentRecovery
+}
@@ -0,0 +1,194 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/mod/sumdb/note"
+	"k8s.io/klog/v2"
+
+	"github.com/transparency-dev/armored-witness/internal/device"
+)
+
+const (
+	selfTestPass = "PASS"
+	selfTestFail = "FAIL"
+	selfTestSkip = "SKIP"
+)
+
+// selfTestCheck is the result of a single self-test check.
+type selfTestCheck struct {
+	name   string
+	status string
+	detail string
+}
+
+// waitForIdentity polls the witness status until the applet reports a witness identity,
+// or --self_test_timeout passes.
+func (p *provisioner) waitForIdentity(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, *selfTestTimeout)
+	defer cancel()
+
+	p.infof("Waiting for the witness applet to report its identity...")
+	t := time.NewTicker(2 * time.Second)
+	defer t.Stop()
+	for {
+		if id := p.status.GetWitness().GetIdentity(); id != "" {
+			p.infof("✅ Witness applet reports identity %s", id)
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("witness applet did not report an identity within %v", *selfTestTimeout)
+		case <-t.C:
+		}
+		s, err := device.WitnessStatus(p.dev)
+		if err != nil {
+			// The applet may still be starting up.
+			klog.V(1).Infof("%sFailed to fetch witness status: %v", p.logPrefix(), err)
+			continue
+		}
+		p.status = s
+	}
+}
+
+// selfTest checks the status most recently reported by the device against the firmware
+// and settings it was provisioned with.
+//
+// It returns a table summarising the checks, which should be shown to the operator,
+// and an error if any of them failed.
+func (p *provisioner) selfTest() (string, error) {
+	checks := []selfTestCheck{
+		p.checkSelfTestHAB(),
+		p.checkSelfTestSRKHash(),
+		p.checkSelfTestOSVersion(),
+		p.checkSelfTestOSRevision(),
+		p.checkSelfTestIdentity(),
+	}
+	checks = append(checks, p.checkSelfTestNetwork()...)
+
+	b := &bytes.Buffer{}
+	w := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tRESULT\tDETAIL")
+	errs := []error{}
+	for _, c := range checks {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c.name, c.status, c.detail)
+		if c.status == selfTestFail {
+			errs = append(errs, fmt.Errorf("%s: %s", c.name, c.detail))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	return b.String(), errors.Join(errs...)
+}
+
+func (p *provisioner) checkSelfTestHAB() selfTestCheck {
+	c := selfTestCheck{name: "HAB", status: selfTestPass, detail: "not fused"}
+	if p.status.HAB {
+		c.detail = "fused"
+	}
+	if p.journal.Fused && !p.status.HAB {
+		c.status, c.detail = selfTestFail, "device was fused, but reports HAB is not enabled"
+	}
+	return c
+}
+
+func (p *provisioner) checkSelfTestSRKHash() selfTestCheck {
+	c := selfTestCheck{name: "SRK hash", status: selfTestPass, detail: p.status.SRKHash}
+	switch {
+	case *habSRKHash == "":
+		c.status, c.detail = selfTestSkip, "no --hab_srk_hash set"
+	case !strings.EqualFold(p.status.SRKHash, *habSRKHash):
+		c.status, c.detail = selfTestFail, fmt.Sprintf("device reports %q, want %q", p.status.SRKHash, *habSRKHash)
+	}
+	return c
+}
+
+func (p *provisioner) checkSelfTestOSVersion() selfTestCheck {
+	c := selfTestCheck{name: "OS version"}
+	r, err := parseManifest(p.fw.trustedOS.bundle.Manifest)
+	if err != nil {
+		c.status, c.detail = selfTestFail, fmt.Sprintf("failed to parse installed manifest: %v", err)
+		return c
+	}
+	got, want := strings.TrimPrefix(p.status.Version, "v"), r.Git.TagName.String()
+	if got != want {
+		c.status, c.detail = selfTestFail, fmt.Sprintf("device reports %q, but %s @ %d was installed", p.status.Version, want, p.fw.trustedOS.bundle.Index)
+		return c
+	}
+	c.status, c.detail = selfTestPass, fmt.Sprintf("%s @ %d", want, p.fw.trustedOS.bundle.Index)
+	return c
+}
+
+func (p *provisioner) checkSelfTestOSRevision() selfTestCheck {
+	c := selfTestCheck{name: "OS revision"}
+	r, err := parseManifest(p.fw.trustedOS.bundle.Manifest)
+	if err != nil {
+		c.status, c.detail = selfTestFail, fmt.Sprintf("failed to parse installed manifest: %v", err)
+		return c
+	}
+	switch got, want := p.status.Revision, r.Git.CommitFingerprint; {
+	case got == "" || want == "":
+		c.status, c.detail = selfTestSkip, "revision not known"
+	case !strings.HasPrefix(want, got):
+		c.status, c.detail = selfTestFail, fmt.Sprintf("device reports %q, but %q was installed", got, want)
+	default:
+		c.status, c.detail = selfTestPass, got
+	}
+	return c
+}
+
+func (p *provisioner) checkSelfTestIdentity() selfTestCheck {
+	c := selfTestCheck{name: "witness identity"}
+	id := p.status.GetWitness().GetIdentity()
+	if _, err := note.NewVerifier(id); err != nil {
+		c.status, c.detail = selfTestFail, fmt.Sprintf("invalid identity %q: %v", id, err)
+		return c
+	}
+	if want := p.journal.WitnessIdentity; want != "" && id != want {
+		c.status, c.detail = selfTestFail, fmt.Sprintf("identity is %q, but was %q when the device first booted the new firmware", id, want)
+		return c
+	}
+	c.status, c.detail = selfTestPass, id
+	return c
+}
+
+func (p *provisioner) checkSelfTestNetwork() []selfTestCheck {
+	link := selfTestCheck{name: "network link", status: selfTestPass, detail: "up"}
+	ip := selfTestCheck{name: "IP address", status: selfTestPass}
+	if w := p.status.GetWitness(); w != nil {
+		ip.detail = w.IP
+	}
+	if *selfTestOffline {
+		link.status, link.detail = selfTestSkip, "--self_test_offline set"
+		ip.status, ip.detail = selfTestSkip, "--self_test_offline set"
+		return []selfTestCheck{link, ip}
+	}
+	if !p.status.Link {
+		link.status, link.detail = selfTestFail, "down"
+	}
+	if ip.detail == "" {
+		ip.status, ip.detail = selfTestFail, "none"
+	}
+	return []selfTestCheck{link, ip}
+}
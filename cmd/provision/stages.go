@@ -0,0 +1,416 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/flynn/u2f/u2fhid"
+	"k8s.io/klog/v2"
+
+	"github.com/transparency-dev/armored-witness-os/api"
+	"github.com/transparency-dev/armored-witness/internal/audit"
+	"github.com/transparency-dev/armored-witness/internal/device"
+	"github.com/transparency-dev/armored-witness/internal/layout"
+	"github.com/transparency-dev/armored-witness/internal/operator"
+)
+
+// provisioner knows how to take a single device through the provisioning stages.
+type provisioner struct {
+	fw          *firmwares
+	jobs        *firmwareJobs
+	recoveryHAB []byte
+
+	journal    *journal
+	journalDir string
+	// rec writes the provisioning record once the device has been provisioned, or is nil
+	// if no record should be written.
+	rec *recorder
+	// op is used to interact with the operator.
+	op operator.Operator
+
+	// bDev is the path to the block device presented by the device while it's running
+	// the recovery image, or empty if the device isn't known to be in that state.
+	bDev string
+	// dev is the opened witness device while it's running the witness firmware, or nil
+	// if the device isn't known to be in that state.
+	dev *u2fhid.Device
+	// status is the most recent status reported by the witness firmware.
+	status *api.Status
+
+	// port is the USB port via which the device being provisioned is connected, or empty
+	// if devices connected to any port should be considered.
+	port string
+	// detected is true once the device has been identified, and recorded in the audit log.
+	detected bool
+}
+
+// newProvisioner creates a provisioner which will install the passed in firmware, and record
+// its progress in journalDir.
+//
+// If rec is not nil, it's used to write a record of each device which is successfully provisioned.
+func newProvisioner(fw *firmwares, journalDir string, rec *recorder, op operator.Operator) (*provisioner, error) {
+	jobs, err := prepareFlashJobs(fw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare flash jobs: %v", err)
+	}
+	klog.Infof("Recovery firmware is %d bytes + %d bytes HAB signature", len(fw.recovery.bundle.Firmware), len(fw.recovery.bundle.HABSignature))
+	return &provisioner{
+		fw:          fw,
+		jobs:        jobs,
+		recoveryHAB: append(fw.recovery.bundle.Firmware, fw.recovery.bundle.HABSignature...),
+		journal:     newJournal(fw, *fuse, *habTarget),
+		journalDir:  journalDir,
+		rec:         rec,
+		op:          op,
+	}, nil
+}
+
+// resume loads the journal for a previous provisioning attempt of the device with the given serial
+// number, so that provisioning picks up from the last completed stage.
+func (p *provisioner) resume(serial string) error {
+	if p.journalDir == "" {
+		return fmt.Errorf("cannot resume provisioning without a journal directory")
+	}
+	j, err := readJournal(p.journalDir, serial)
+	if err != nil {
+		return fmt.Errorf("failed to read journal for %s: %v", serial, err)
+	}
+	if j.done() {
+		return fmt.Errorf("provisioning of %s has already completed", serial)
+	}
+	if !p.journal.compatible(j) {
+		return fmt.Errorf("journal for %s was recorded with different firmware or settings (%+v, fuse=%v, hab_target=%q)", serial, j.Firmware, j.Fuse, j.HABTarget)
+	}
+	p.journal = j
+	p.infof("Resuming provisioning of %s after stage %q", serial, j.Completed)
+	return nil
+}
+
+// run performs all outstanding provisioning stages.
+func (p *provisioner) run(ctx context.Context) error {
+	defer func() {
+		if p.dev != nil {
+			p.dev.Close()
+		}
+	}()
+
+	for !p.journal.done() {
+		s := p.journal.next()
+		p.op.Progress(p.port, string(s), slices.Index(stages, s)+1, len(stages))
+		if err := p.runStage(ctx, s); err != nil {
+			if p.journal.path != "" {
+				p.infof("Provisioning of %s can be resumed by re-running with --resume=%s", p.journal.Serial, p.journal.Serial)
+			}
+			p.audit(audit.EventFailed, map[string]string{"stage": string(s), "error": err.Error()})
+			return fmt.Errorf("stage %q: %v", s, err)
+		}
+		if err := p.journal.complete(s); err != nil {
+			return fmt.Errorf("failed to record completion of stage %q: %v", s, err)
+		}
+	}
+	p.audit(audit.EventProvisioned, map[string]string{
+		"boot_index":     fmt.Sprintf("%d", p.journal.Firmware.Boot),
+		"recovery_index": fmt.Sprintf("%d", p.journal.Firmware.Recovery),
+		"os_index":       fmt.Sprintf("%d", p.journal.Firmware.OS),
+		"applet_index":   fmt.Sprintf("%d", p.journal.Firmware.Applet),
+	})
+	return nil
+}
+
+// runStage performs the work for a single provisioning stage.
+//
+// Each stage ensures the device is in the state it needs to be, so that stages can be
+// performed correctly when resuming from a journal.
+func (p *provisioner) runStage(ctx context.Context, s stage) error {
+	switch s {
+	case stageRecoveryBoot:
+		return p.ensureRecovery(ctx, "connect unprovisioned device")
+
+	case stageFlash:
+		if err := p.ensureRecovery(ctx, "reboot device"); err != nil {
+			return err
+		}
+		// Don't let a provisioning run quietly replace newer firmware which may already
+		// be on the device, e.g. because of a stale --os_index or --applet_index.
+		targets := upgradeTargets(p.fw, p.jobs)
+		table, err := checkRollback(p.bDev, []upgradeTarget{targets["boot"], targets["os"], targets["applet"]})
+		p.infof("Installed firmware:\n%s", table)
+		if err != nil {
+			return err
+		}
+		jobs := []flashJob{p.jobs.trustedOS, p.jobs.bootloader, p.jobs.bootloaderConfig}
+		if *fuse {
+			// If we need to fuse the device, we'll install the applet later on.
+			// This is ensure there's no unexpected CPU load on the device when
+			// we attempt to set fuses as this has been known to be timing sensitive.
+			//
+			// Add an extra job to destroy any pre-existing applet installed on the device.
+			jobs = append(jobs, flashJob{name: "DUMMY_APPLET", img: make([]byte, len(p.jobs.trustedApplet.img)), block: p.jobs.trustedApplet.block})
+		} else {
+			// We're not fusing, so can just install everything now.
+			jobs = append(jobs, p.jobs.trustedApplet)
+		}
+		if err := p.op.Confirm(ctx, p.port, "flash firmware images"); err != nil {
+			return err
+		}
+		p.infof("Flashing images...")
+		if err := flashImages(p.bDev, jobs); err != nil {
+			return fmt.Errorf("error while flashing images: %v", err)
+		}
+		p.infof("✅ Flashed images")
+		for _, j := range jobs {
+			p.audit(audit.EventFlashed, flashedDetails(j))
+		}
+
+	case stageWipe:
+		if !*wipeWitness {
+			return nil
+		}
+		if err := p.ensureRecovery(ctx, "reboot device"); err != nil {
+			return err
+		}
+		if err := wipeAppletData(p.bDev); err != nil {
+			return fmt.Errorf("error while wiping applet data: %v", err)
+		}
+		p.audit(audit.EventWiped, map[string]string{"region": layout.AppletData.String()})
+
+	case stageBootOS:
+		if err := p.ensureOS(ctx); err != nil {
+			return err
+		}
+		if !*fuse {
+			// The applet has already been installed, so remember its identity to check
+			// it's unchanged once provisioning is complete.
+			p.journal.WitnessIdentity = p.status.GetWitness().GetIdentity()
+		}
+		return p.checkStatus()
+
+	case stageFuse:
+		if !*fuse {
+			return nil
+		}
+		if p.journal.Fused {
+			p.infof("✅ Device %s has already been fused", p.journal.Serial)
+			return nil
+		}
+		if p.dev == nil {
+			// We're resuming an interrupted attempt, so check the device over again
+			// before doing anything irreversible.
+			if err := p.ensureOS(ctx); err != nil {
+				return err
+			}
+			if p.status.HAB {
+				// The device passed the checks in stageBootOS during the previous attempt, so
+				// it must have been fused by us before the journal could be updated.
+				p.infof("✅ Device %s reports HAB is already enabled", p.journal.Serial)
+				p.journal.Fused = true
+				return nil
+			}
+			if err := p.checkStatus(); err != nil {
+				return err
+			}
+		}
+		p.warningf("\n%s\n", fuseWarning)
+		p.infof("\n%s", p.fusePlan())
+		if err := p.authorizeFuse(ctx); err != nil {
+			return err
+		}
+		p.infof("Attempting to fuse device and activate HAB 🫣")
+		if err := device.ActivateHAB(p.dev); err != nil {
+			err = fmt.Errorf("device failed to activate HAB: %v", err)
+			if !*runAnyway {
+				return err
+			}
+			// The device isn't recorded as fused, so a later attempt still refuses to
+			// continue if it turns out HAB was set after all.
+			p.warningf("⚠️  %s, continuing anyway", err.Error())
+		} else {
+			p.infof("✅ Fusing successful! 👌")
+			p.journal.Fused = true
+			p.audit(audit.EventFused, map[string]string{
+				"hab_target":         *habTarget,
+				"srk_hash":           p.status.SRKHash,
+				"fuse_authorization": sha256Hex([]byte(p.journal.FuseAuthorization)),
+			})
+		}
+		// The device needs to be rebooted before we can talk to it again.
+		p.dev.Close()
+		p.dev = nil
+
+	case stageFlashApplet:
+		if !*fuse {
+			return nil
+		}
+		p.infof("1 remaining firmware to install")
+		if err := p.ensureRecovery(ctx, "reboot device"); err != nil {
+			return err
+		}
+		if err := p.op.Confirm(ctx, p.port, "flash Applet image"); err != nil {
+			return err
+		}
+		p.infof("Flashing Applet image...")
+		if err := flashImages(p.bDev, []flashJob{p.jobs.trustedApplet}); err != nil {
+			return fmt.Errorf("error while flashing Applet image: %v", err)
+		}
+		p.infof("✅ Flashed Applet image")
+		p.audit(audit.EventFlashed, flashedDetails(p.jobs.trustedApplet))
+
+	case stageStatus:
+		if err := p.ensureOS(ctx); err != nil {
+			return err
+		}
+		if err := p.waitForIdentity(ctx); err != nil {
+			return err
+		}
+		table, err := p.selfTest()
+		p.infof("Self-test results:\n%s", table)
+		result := "pass"
+		if err != nil {
+			result = "fail"
+		}
+		p.audit(audit.EventStatus, map[string]string{
+			"hab":              fmt.Sprintf("%t", p.status.HAB),
+			"srk_hash":         p.status.SRKHash,
+			"witness_identity": p.status.Witness.GetIdentity(),
+			"self_test":        result,
+		})
+		if err != nil {
+			return fmt.Errorf("self-test failed: %v", err)
+		}
+		p.infof("✅ Self-test passed")
+		p.infof("✅ Witness ID %s provisioned", p.status.Witness.GetIdentity())
+
+		if p.rec == nil {
+			return nil
+		}
+		r, err := p.newRecord()
+		if err != nil {
+			return fmt.Errorf("failed to create provisioning record: %v", err)
+		}
+		f, err := p.rec.write(r)
+		if err != nil {
+			return err
+		}
+		p.infof("✅ Provisioning record written to %s", f)
+
+	default:
+		return fmt.Errorf("unknown stage %q", s)
+	}
+	return nil
+}
+
+// ensureRecovery boots the device into the recovery image, unless it's already known to be running it.
+// connect is the action the operator should take to make the device available.
+func (p *provisioner) ensureRecovery(ctx context.Context, connect string) error {
+	if p.bDev != "" {
+		return nil
+	}
+	if p.dev != nil {
+		p.dev.Close()
+		p.dev = nil
+	}
+	p.op.Instruct(p.port, operator.Instruction{Switch: operator.BootSwitchUSB, Action: connect})
+
+	// The device will initially be in HID mode (showing as "RecoveryMode" in the output to lsusb).
+	// So we'll detect it as such:
+	target, bDev, err := device.BootIntoRecoveryOnPort(ctx, p.port, p.recoveryHAB, *blockDeviceGlob)
+	if err != nil {
+		return err
+	}
+	p.infof("✅ Detected device %q", target.DeviceInfo.Path)
+	p.infof("✅ Detected blockdevice %v", bDev)
+	p.bDev = bDev
+
+	if serial := device.SerialFromBlockDevice(bDev); serial != "" {
+		return p.attach(serial)
+	}
+	return nil
+}
+
+// ensureOS waits for the device to boot into the witness firmware, unless it's already known to be running it,
+// and fetches its status.
+func (p *provisioner) ensureOS(ctx context.Context) error {
+	if p.dev != nil {
+		return nil
+	}
+	p.op.Instruct(p.port, operator.Instruction{Switch: operator.BootSwitchMMC, Action: "reboot device"})
+	p.infof("Waiting for device to boot...")
+	p.bDev = ""
+
+	path, dev, err := device.WaitForU2F(ctx, p.port)
+	if err != nil {
+		return fmt.Errorf("failed to find armored witness device: %v", err)
+	}
+	p.infof("✅ Detected device %q", path)
+	p.dev = dev
+
+	s, err := device.WitnessStatus(dev)
+	if err != nil {
+		return fmt.Errorf("failed to fetch witness status: %v", err)
+	}
+	p.status = s
+	p.infof("✅ Witness serial number %s found", s.Serial)
+	return p.attach(s.Serial)
+}
+
+// checkStatus checks that the HAB state and SRK hash reported by the device are suitable
+// for the requested provisioning.
+func (p *provisioner) checkStatus() error {
+	s := p.status
+	if s.HAB {
+		if *fuse && !p.journal.Fused && !*runAnyway {
+			return fmt.Errorf("witness serial number %s has HAB fuse set", s.Serial)
+		}
+		p.infof("⚠️  Witness serial number %s is already HAB fused", s.Serial)
+	} else {
+		p.infof("✅ Witness serial number %s is not HAB fused", s.Serial)
+	}
+
+	switch {
+	case *habSRKHash == "":
+		p.warningf("⚠️  No --hab_srk_hash set, unable to check witness OS SRK Hash '%s'", s.SRKHash)
+	case !strings.EqualFold(s.SRKHash, *habSRKHash):
+		e := fmt.Errorf("witness OS reports SRK Hash '%s', but release environment %q expects '%s', not fusing", s.SRKHash, *habTarget, *habSRKHash)
+		if *fuse {
+			return e
+		}
+		p.warningf("⚠️  %s", e.Error())
+	default:
+		p.infof("✅ Witness OS reports expected SRK Hash for release environment %q", *habTarget)
+	}
+	return nil
+}
+
+// infof logs an informational message, prefixed with the USB port of the device being
+// provisioned when there may be several devices being provisioned at once.
+func (p *provisioner) infof(format string, args ...any) {
+	klog.InfofDepth(1, p.logPrefix()+format, args...)
+}
+
+// warningf logs a warning, prefixed in the same way as infof.
+func (p *provisioner) warningf(format string, args ...any) {
+	klog.WarningfDepth(1, p.logPrefix()+format, args...)
+}
+
+func (p *provisioner) logPrefix() string {
+	if p.port == "" {
+		return ""
+	}
+	return fmt.Sprintf("[port %s] ", p.port)
+}
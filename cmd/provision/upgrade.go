@@ -0,0 +1,192 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"github.com/transparency-dev/armored-witness-common/release/firmware/ftlog"
+	"github.com/transparency-dev/armored-witness/internal/audit"
+	"github.com/transparency-dev/armored-witness/internal/device"
+	"github.com/transparency-dev/armored-witness/internal/layout"
+	"github.com/transparency-dev/armored-witness/internal/operator"
+)
+
+// upgradeComponents lists the names of the components which can be passed to --upgrade.
+var upgradeComponents = []string{"boot", "os", "applet"}
+
+// upgradeTarget describes how to upgrade a single component.
+type upgradeTarget struct {
+	name string
+	// cfgBlock is the MMC block where the config for the installed firmware is stored.
+	cfgBlock int64
+	// fw is the firmware to be installed.
+	fw *fw
+	// jobs are the flash jobs which install the firmware.
+	jobs []flashJob
+}
+
+// upgradeTargets returns the upgrade targets for each of the upgradeComponents, keyed by name.
+func upgradeTargets(fw *firmwares, jobs *firmwareJobs) map[string]upgradeTarget {
+	return map[string]upgradeTarget{
+		"boot":   {name: "boot", cfgBlock: layout.BootloaderConfig.Block, fw: fw.bootloader, jobs: []flashJob{jobs.bootloader, jobs.bootloaderConfig}},
+		"os":     {name: "os", cfgBlock: layout.OS.Block, fw: fw.trustedOS, jobs: []flashJob{jobs.trustedOS}},
+		"applet": {name: "applet", cfgBlock: layout.Applet.Block, fw: fw.trustedApplet, jobs: []flashJob{jobs.trustedApplet}},
+	}
+}
+
+// parseUpgradeComponents parses the comma separated list of components passed to --upgrade.
+func parseUpgradeComponents(s string) ([]string, error) {
+	r := []string{}
+	for _, c := range strings.Split(s, ",") {
+		c = strings.TrimSpace(c)
+		if !slices.Contains(upgradeComponents, c) {
+			return nil, fmt.Errorf("unknown component %q, must be one of %v", c, upgradeComponents)
+		}
+		if !slices.Contains(r, c) {
+			r = append(r, c)
+		}
+	}
+	return r, nil
+}
+
+// runUpgrade boots a previously provisioned device into recovery mode, and reflashes only
+// the requested components, leaving everything else on the MMC (including applet data) alone.
+//
+// Components are not downgraded, unless the --allow_downgrade flag is set.
+func runUpgrade(ctx context.Context, fw *firmwares, op operator.Operator, components []string) error {
+	jobs, err := prepareFlashJobs(fw)
+	if err != nil {
+		return fmt.Errorf("failed to prepare flash jobs: %v", err)
+	}
+	targets := upgradeTargets(fw, jobs)
+
+	op.Instruct("", operator.Instruction{Switch: operator.BootSwitchUSB, Action: "connect device to be upgraded"})
+	recoveryHAB := append(fw.recovery.bundle.Firmware, fw.recovery.bundle.HABSignature...)
+	target, bDev, err := device.BootIntoRecovery(ctx, recoveryHAB, *blockDeviceGlob)
+	if err != nil {
+		return err
+	}
+	klog.Infof("✅ Detected device %q", target.DeviceInfo.Path)
+	klog.Infof("✅ Detected blockdevice %v", bDev)
+	serial := device.SerialFromBlockDevice(bDev)
+	writeAudit(serial, audit.EventDetected, nil)
+
+	selected := []upgradeTarget{}
+	toFlash := []flashJob{}
+	for _, c := range components {
+		selected = append(selected, targets[c])
+		toFlash = append(toFlash, targets[c].jobs...)
+	}
+	if slices.Contains(components, "boot") {
+		if err := checkHABTarget(bDev); err != nil {
+			return err
+		}
+	}
+	table, err := checkRollback(bDev, selected)
+	klog.Infof("Installed firmware:\n%s", table)
+	if err != nil {
+		return err
+	}
+
+	if err := op.Confirm(ctx, "", fmt.Sprintf("flash %s", strings.Join(components, ", "))); err != nil {
+		return err
+	}
+	if err := flashImages(bDev, toFlash); err != nil {
+		return fmt.Errorf("error while flashing images: %v", err)
+	}
+	klog.Info("✅ Flashed images")
+	for _, j := range toFlash {
+		writeAudit(serial, audit.EventFlashed, flashedDetails(j))
+	}
+	writeAudit(serial, audit.EventUpgraded, map[string]string{"components": strings.Join(components, ",")})
+	op.Instruct("", operator.Instruction{Switch: operator.BootSwitchMMC, Action: "reboot device"})
+	return nil
+}
+
+// checkHABTarget returns an error unless the bootloader installed on bDev is for the
+// same HAB target as the bootloader being installed, since one signed for any other
+// target will not boot on a fused device.
+//
+// The check is skipped if the --allow_hab_target_change flag is set.
+func checkHABTarget(bDev string) error {
+	if *allowHABTargetChange {
+		klog.Warningf("⚠️  Not checking the HAB target of the installed bootloader. Upgrading the bootloader on a fused device with firmware for a HAB target other than the one it was fused to (%q) will brick it.", *habTarget)
+		return nil
+	}
+	got, _, err := installedRelease(bDev, layout.BootloaderConfig.Block)
+	if err != nil {
+		return fmt.Errorf("failed to read the HAB target of the installed bootloader, use --allow_hab_target_change to upgrade it anyway: %v", err)
+	}
+	if got.HAB == nil || got.HAB.Target != *habTarget {
+		gotTarget := ""
+		if got.HAB != nil {
+			gotTarget = got.HAB.Target
+		}
+		return fmt.Errorf("installed bootloader is for HAB target %q, not %q, use --allow_hab_target_change to upgrade it anyway", gotTarget, *habTarget)
+	}
+	klog.Infof("✅ Installed bootloader is for HAB target %q", got.HAB.Target)
+	return nil
+}
+
+// installedRelease reads the config stored at cfgBlock on the MMC, and returns the
+// manifest and FT log index of the firmware it describes.
+func installedRelease(dev string, cfgBlock int64) (*ftlog.FirmwareRelease, uint64, error) {
+	f, err := os.OpenFile(dev, os.O_RDONLY, 0o400)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error opening %v: %v", dev, err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			klog.Errorf("Error closing %v: %v", dev, err)
+		}
+	}()
+
+	cfg, _, err := layout.ReadConfig(f, cfgBlock)
+	if err != nil {
+		return nil, 0, err
+	}
+	r, err := parseManifest(cfg.Bundle.Manifest)
+	if err != nil {
+		return nil, 0, err
+	}
+	return r, cfg.Bundle.LogIndex, nil
+}
+
+// parseManifest parses the body of a manifest note without verifying its signatures.
+//
+// This is only suitable for deciding whether an install is an upgrade, anyone who can
+// write to the MMC could have written whatever manifest they liked.
+func parseManifest(m []byte) (*ftlog.FirmwareRelease, error) {
+	// The signatures are separated from the note text by a blank line.
+	i := bytes.LastIndex(m, []byte("\n\n"))
+	if i < 0 {
+		return nil, errors.New("malformed manifest note")
+	}
+	r := &ftlog.FirmwareRelease{}
+	if err := json.Unmarshal(m[:i+1], r); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal manifest: %v", err)
+	}
+	return r, nil
+}
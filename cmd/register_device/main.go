@@ -0,0 +1,120 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// register_device is a tool which adds the identities of provisioned devices to the
+// devices directory, using the signed provisioning records written by the provision tool.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/transparency-dev/armored-witness/internal/record"
+	"golang.org/x/mod/sumdb/note"
+	"k8s.io/klog/v2"
+)
+
+const usageString = `
+This program adds provisioned devices to the devices directory.
+
+It reads one or more provisioning records written by the provision tool, verifies
+their signatures, and writes the device identity files into the subdirectory of
+--devices_dir named after the release environment the device was fused to:
+$ register_device --record_verifier=<verifier string> [--devices_dir=devices] <record file>...
+`
+
+var (
+	recordVerifier = flag.String("record_verifier", "", "Verifier string for the key used to sign provisioning records.")
+	devicesDir     = flag.String("devices_dir", "devices", "Path to the devices directory.")
+	allowUnfused   = flag.Bool("allow_unfused", false, "If set, devices which are not HAB fused may be registered.")
+)
+
+func main() {
+	klog.InitFlags(nil)
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), usageString+"\n\n")
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "Flags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *recordVerifier == "" {
+		klog.Exit("--record_verifier is required.")
+	}
+	if flag.NArg() == 0 {
+		klog.Exit("At least one provisioning record must be provided.")
+	}
+	v, err := note.NewVerifier(*recordVerifier)
+	if err != nil {
+		klog.Exitf("Invalid --record_verifier: %v", err)
+	}
+
+	errs := []error{}
+	for _, f := range flag.Args() {
+		if err := register(f, v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", f, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		klog.Exitf("❌ Failed to register devices:\n%v", err)
+	}
+}
+
+// register verifies the provisioning record in the file f, and writes the identity files for
+// the device it describes.
+func register(f string, v note.Verifier) error {
+	b, err := os.ReadFile(f)
+	if err != nil {
+		return err
+	}
+	r, err := record.Open(b, note.VerifierList(v))
+	if err != nil {
+		return err
+	}
+	if !r.HAB && !*allowUnfused {
+		return fmt.Errorf("device %s is not HAB fused", r.Serial)
+	}
+	if r.HABTarget != "ci" && r.HABTarget != "prod" {
+		return fmt.Errorf("device %s has unknown release environment %q", r.Serial, r.HABTarget)
+	}
+	files, err := r.DeviceFiles()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(*devicesDir, r.HABTarget)
+	// Check everything before writing anything, so we don't leave a partially registered device.
+	for n, c := range files {
+		e, err := os.ReadFile(filepath.Join(dir, n))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return err
+		case !bytes.Equal(e, c):
+			return fmt.Errorf("%s already exists with different contents", filepath.Join(dir, n))
+		}
+	}
+	for n, c := range files {
+		if err := os.WriteFile(filepath.Join(dir, n), c, 0o644); err != nil {
+			return err
+		}
+	}
+	klog.Infof("✅ Registered device %s (witness %s) in %s", r.Serial, r.WitnessIdentity, dir)
+	return nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/transparency-dev/armored-witness-common/release/firmware"
+	"github.com/transparency-dev/armored-witness-common/release/firmware/ftlog"
+	"github.com/transparency-dev/armored-witness-common/release/firmware/update"
+	"github.com/transparency-dev/armored-witness/internal/fetcher"
+	"github.com/transparency-dev/armored-witness/internal/layout"
+	"github.com/usbarmory/armory-boot/sdp"
+	"k8s.io/klog/v2"
+)
+
+// maxHABSignatureSize is the largest HAB signature which will be read from after the
+// bootloader firmware; signatures are typically a few KiB.
+const maxHABSignatureSize = 1 << 20
+
+// readHABArea reads the part of the bootloader region of the MMC which follows the
+// bootloader firmware, and should hold its HAB signature.
+//
+// fwEnd is the MMC byte offset of the end of the bootloader firmware.
+func readHABArea(f *os.File, fwEnd int64) ([]byte, error) {
+	r := layout.Bootloader
+	n := min(r.Offset()+r.Size()-fwEnd, maxHABSignatureSize)
+	if n <= 0 {
+		return nil, nil
+	}
+	b := make([]byte, n)
+	if _, err := f.ReadAt(b, fwEnd); err != nil {
+		return nil, fmt.Errorf("failed to read HAB signature area: %v", err)
+	}
+	return b, nil
+}
+
+// habSignatureSpace returns the space the IVT of the bootloader IMX image imx reserves for
+// its HAB signature (CSF), which is appended to the image.
+//
+// The IVT at the start of the image points at the CSF, and its boot data gives the length
+// of the whole image as loaded by the ROM, including the CSF. The signature itself may be
+// shorter than the space reserved for it.
+func habSignatureSpace(imx []byte) (int64, error) {
+	ivt, err := sdp.ParseIVT(imx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse bootloader IVT: %v", err)
+	}
+	if ivt.CSF == 0 {
+		return 0, errors.New("bootloader IVT has no CSF pointer")
+	}
+	if off := int64(ivt.CSF) - int64(ivt.Self); off != int64(len(imx)) {
+		return 0, fmt.Errorf("bootloader IVT places the HAB signature at offset 0x%x, not after the 0x%x byte image", off, len(imx))
+	}
+	off := int64(ivt.BootData) - int64(ivt.Self)
+	if off < 0 || off+8 > int64(len(imx)) {
+		return 0, fmt.Errorf("bootloader IVT boot data pointer 0x%x is outside the image", ivt.BootData)
+	}
+	start := int64(binary.LittleEndian.Uint32(imx[off:]))
+	length := int64(binary.LittleEndian.Uint32(imx[off+4:]))
+	n := start + length - int64(ivt.CSF)
+	if n <= 0 || n > maxHABSignatureSize {
+		return 0, fmt.Errorf("bootloader IVT reserves invalid HAB signature length 0x%x", n)
+	}
+	return n, nil
+}
+
+// checkHABSignature checks that the bootloader manifest m is for --hab_target, and that area,
+// the data which follows the bootloader firmware imx on the MMC, starts with a HAB signature
+// whose hash matches the one in m.
+//
+// The release pipeline (see cmd/manifest) hashes the whole signature file produced by
+// habtool, which may be shorter than the space the IVT reserves for it. So the signature is
+// taken to be as long as released, the published signature file for the release. If that
+// isn't available, the whole reserved space is hashed, which only matches if the signature
+// fills it.
+//
+// Returns the signature found.
+func checkHABSignature(m *ftlog.FirmwareRelease, imx []byte, area []byte, released []byte) ([]byte, error) {
+	if m.HAB == nil {
+		return nil, errors.New("manifest has no HAB information")
+	}
+	if *habTarget != "" && m.HAB.Target != *habTarget {
+		return nil, fmt.Errorf("manifest is for HAB target %q, not %q", m.HAB.Target, *habTarget)
+	}
+	want := m.HAB.SignatureDigestSha256
+	if len(want) == 0 {
+		return nil, errors.New("manifest does not commit to a HAB signature")
+	}
+	n, err := habSignatureSpace(imx)
+	if err != nil {
+		return nil, err
+	}
+	if released != nil {
+		if got := sha256.Sum256(released); !bytes.Equal(got[:], want) {
+			return nil, fmt.Errorf("published HAB signature has hash %x, want %x", got, want)
+		}
+		if int64(len(released)) > n {
+			return nil, fmt.Errorf("0x%x byte HAB signature doesn't fit in the 0x%x bytes the bootloader IVT reserves for it", len(released), n)
+		}
+		n = int64(len(released))
+	}
+	if n > int64(len(area)) {
+		return nil, fmt.Errorf("0x%x byte HAB signature doesn't fit in the bootloader region", n)
+	}
+	sig := area[:n]
+	if bytes.Equal(sig, make([]byte, len(sig))) {
+		return nil, errors.New("HAB signature is missing")
+	}
+	if got := sha256.Sum256(sig); !bytes.Equal(got[:], want) {
+		if released == nil {
+			return nil, fmt.Errorf("0x%x bytes reserved for the HAB signature following the bootloader have hash %x, want %x; the published signature wasn't available to tell if it's shorter than that", n, got, want)
+		}
+		return nil, fmt.Errorf("HAB signature following the bootloader has hash %x, want %x", got, want)
+	}
+	return sig, nil
+}
+
+// releasedHABSignature returns the HAB signature file published with the bootloader release
+// in b, whose manifest m has been verified.
+//
+// When verifying offline, it's only available if the firmware pack holds the same release,
+// and nil is returned otherwise.
+func (v *verifier) releasedHABSignature(ctx context.Context, b firmware.Bundle, m *ftlog.FirmwareRelease) ([]byte, error) {
+	if v.pack != nil {
+		if bytes.Equal(v.pack.Boot.Manifest, b.Manifest) {
+			return v.pack.Boot.HABSignature, nil
+		}
+		return nil, nil
+	}
+	p, err := update.HABSignaturePath(*m)
+	if err != nil {
+		return nil, fmt.Errorf("HABSignaturePath: %v", err)
+	}
+	sig, err := fetcher.New(v.binBaseURL)(ctx, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch published HAB signature from %q: %v", p, err)
+	}
+	return sig, nil
+}
+
+// verifyHABSignature checks the HAB signature found after the firmware in b for the named
+// component, recording the result in r.
+//
+// The check can only be made once the component's manifest has been verified.
+// Returns the signature, if one matching the manifest was found.
+func (v *verifier) verifyHABSignature(ctx context.Context, name string, b firmware.Bundle, area []byte, r *componentReport) ([]byte, error) {
+	if r.Manifest == nil {
+		r.HABSignature = resultSkipped
+		klog.Warningf("  ⚠️  %s: HAB signature not checked as manifest could not be verified", name)
+		return nil, nil
+	}
+	if r.Manifest.HAB == nil && *habTarget == "" {
+		r.HABSignature = resultSkipped
+		klog.Warningf("  ⚠️  %s: HAB signature not checked as manifest has no HAB information, and no --hab_target is set", name)
+		return nil, nil
+	}
+	var released []byte
+	if r.Manifest.HAB != nil && len(r.Manifest.HAB.SignatureDigestSha256) > 0 {
+		var err error
+		if released, err = v.releasedHABSignature(ctx, b, r.Manifest); err != nil {
+			r.HABSignature = resultFailed
+			klog.Infof("  ❌ %s: %v", name, err)
+			return nil, err
+		}
+	}
+	sig, err := checkHABSignature(r.Manifest, b.Firmware, area, released)
+	if err != nil {
+		r.HABSignature = resultFailed
+		klog.Infof("  ❌ %s: %v", name, err)
+		return nil, err
+	}
+	r.HABSignature = resultOK
+	klog.Infof("  ✅ %s: %d byte HAB signature for %s matches manifest", name, len(sig), r.Manifest.HAB.Target)
+	return sig, nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"github.com/transparency-dev/armored-witness-common/release/firmware/ftlog"
+)
+
+// testIMX returns an IMX image of size bytes whose IVT reserves space for a sigSpace byte
+// CSF straight after it. Only the IVT and boot data are filled in.
+func testIMX(size, sigSpace uint32) []byte {
+	const self, start = 0x80800000, 0x80800000 - 0x400
+	imx := make([]byte, size)
+	imx[0], imx[1], imx[2], imx[3] = 0xd1, 0x00, 0x20, 0x40
+	le := binary.LittleEndian
+	le.PutUint32(imx[16:], self+0x20) // Boot data
+	le.PutUint32(imx[20:], self)
+	le.PutUint32(imx[24:], self+size) // CSF
+	le.PutUint32(imx[32:], start)
+	le.PutUint32(imx[36:], self+size+sigSpace-start)
+	return imx
+}
+
+func TestCheckHABSignature(t *testing.T) {
+	// The space the IVT reserves for the signature isn't necessarily all used by the
+	// signature file which the release pipeline hashes, so the signature here is shorter.
+	imx := testIMX(0x1000, 0x200)
+	sig := make([]byte, 0x180)
+	for i := range sig {
+		sig[i] = byte(i)
+	}
+	h := sha256.Sum256(sig)
+	m := &ftlog.FirmwareRelease{HAB: &ftlog.HAB{Target: "ci", SignatureDigestSha256: h[:]}}
+	// The rest of the reserved space is padded, and the MMC carries on after that.
+	area := append(append([]byte{}, sig...), make([]byte, 0x80)...)
+	area = append(area, 0xff, 0xff)
+
+	tampered := append([]byte{}, area...)
+	tampered[3] ^= 1
+	otherSig := append([]byte{}, sig...)
+	otherSig[0] ^= 1
+	// A signature which fills all the space reserved for it.
+	full := append([]byte{}, area[:0x200]...)
+	full[0x1ff] = 1
+	fullHash := sha256.Sum256(full)
+	fullM := &ftlog.FirmwareRelease{HAB: &ftlog.HAB{Target: "ci", SignatureDigestSha256: fullHash[:]}}
+
+	for _, test := range []struct {
+		desc     string
+		m        *ftlog.FirmwareRelease
+		imx      []byte
+		area     []byte
+		released []byte
+		wantLen  int
+		wantErr  bool
+	}{
+		{desc: "published signature", m: m, imx: imx, area: area, released: sig, wantLen: len(sig)},
+		{desc: "tampered", m: m, imx: imx, area: tampered, released: sig, wantErr: true},
+		{desc: "missing", m: m, imx: imx, area: make([]byte, 0x400), released: sig, wantErr: true},
+		{desc: "truncated", m: m, imx: imx, area: sig[:0x80], released: sig, wantErr: true},
+		{desc: "published signature doesn't match manifest", m: m, imx: imx, area: area, released: otherSig, wantErr: true},
+		{desc: "published signature larger than reserved", m: m, imx: testIMX(0x1000, 0x100), area: area, released: sig, wantErr: true},
+		{desc: "CSF not after image", m: m, imx: testIMX(0x1000, 0x200)[:0x800], area: area, released: sig, wantErr: true},
+		{desc: "unpublished, filling reserved space", m: fullM, imx: imx, area: append(full, 0xff), wantLen: len(full)},
+		// Without the published signature, its length can't be told.
+		{desc: "unpublished, shorter than reserved space", m: m, imx: imx, area: area, wantErr: true},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := checkHABSignature(test.m, test.imx, test.area, test.released)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("checkHABSignature: got err %v, want err %t", err, test.wantErr)
+			}
+			if err == nil && len(got) != test.wantLen {
+				t.Errorf("checkHABSignature returned %d byte signature, want %d", len(got), test.wantLen)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// dumpChunkSize is the amount of MMC data read at a time while dumping.
+const dumpChunkSize = 1 << 20
+
+// dumpMMC copies the entire contents of the MMC at dev into a new image file at path,
+// returning the hex encoded SHA256 hash of the contents.
+//
+// Chunks of the MMC which are entirely zero are left as holes in the image file, on
+// filesystems which support sparse files.
+func dumpMMC(dev, path string) (string, error) {
+	in, err := os.Open(dev)
+	if err != nil {
+		return "", fmt.Errorf("error opening %v: %v", dev, err)
+	}
+	defer func() {
+		if err := in.Close(); err != nil {
+			klog.Errorf("Error closing %v: %v", dev, err)
+		}
+	}()
+	size, err := in.Seek(0, io.SeekEnd)
+	if err != nil {
+		return "", fmt.Errorf("failed to determine size of %v: %v", dev, err)
+	}
+
+	// Refuse to clobber an existing file, it may well be evidence from another device.
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("failed to create image file: %v", err)
+	}
+	if err := out.Truncate(size); err != nil {
+		_ = out.Close()
+		return "", fmt.Errorf("failed to size image file: %v", err)
+	}
+
+	klog.Infof("Dumping 0x%x bytes of MMC from %s to %s...", size, dev, path)
+	h := sha256.New()
+	buf := make([]byte, dumpChunkSize)
+	zero := make([]byte, dumpChunkSize)
+	t := time.NewTicker(5 * time.Second)
+	defer t.Stop()
+	for off := int64(0); off < size; off += dumpChunkSize {
+		select {
+		case <-t.C:
+			klog.Infof("   %3d%%", (off*100)/size)
+		default:
+		}
+		b := buf[:min(dumpChunkSize, size-off)]
+		if _, err := in.ReadAt(b, off); err != nil {
+			_ = out.Close()
+			return "", fmt.Errorf("failed to read MMC at offset 0x%x: %v", off, err)
+		}
+		h.Write(b)
+		if bytes.Equal(b, zero[:len(b)]) {
+			continue
+		}
+		if _, err := out.WriteAt(b, off); err != nil {
+			_ = out.Close()
+			return "", fmt.Errorf("failed to write image at offset 0x%x: %v", off, err)
+		}
+	}
+	klog.Info("   100%")
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return "", fmt.Errorf("failed to sync image file: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		return "", fmt.Errorf("failed to close image file: %v", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFile returns the hex encoded SHA256 hash of the contents of the file or block device at path.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			klog.Errorf("Error closing %v: %v", path, err)
+		}
+	}()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read %v: %v", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -18,11 +18,10 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
-	"encoding/gob"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -30,15 +29,21 @@ import (
 	"net/url"
 	"os"
 	"os/user"
+	"slices"
 	"time"
 
 	"k8s.io/klog/v2"
 
-	"github.com/transparency-dev/armored-witness-boot/config"
 	"github.com/transparency-dev/armored-witness-common/release/firmware"
+	"github.com/transparency-dev/armored-witness-common/release/firmware/ftlog"
 	"github.com/transparency-dev/armored-witness-common/release/firmware/update"
+	"github.com/transparency-dev/armored-witness/internal/audit"
 	"github.com/transparency-dev/armored-witness/internal/device"
 	"github.com/transparency-dev/armored-witness/internal/fetcher"
+	"github.com/transparency-dev/armored-witness/internal/layout"
+	"github.com/transparency-dev/armored-witness/internal/operator"
+	"github.com/transparency-dev/armored-witness/internal/pack"
+	"github.com/transparency-dev/armored-witness/internal/policy"
 	"github.com/transparency-dev/armored-witness/internal/release"
 	"github.com/transparency-dev/formats/log"
 	"github.com/transparency-dev/merkle/proof"
@@ -48,29 +53,16 @@ import (
 	"golang.org/x/mod/sumdb/note"
 )
 
-const (
-	// Block size in bytes of the MMC device on the armored witness.
-	mmcBlockSize = 512
-
-	// bootloaderConfigBlock defines the location of the bootloader config GOB on MMC.
-	// In constrast to the other firmware binaries below where each firmware is preceeded
-	// by its config GOB, the bootloader config is stored separatly due to the hard requirement
-	// for the binary location imposed by the i.MX ROM bootloader.
-	bootloaderConfigBlock = 0x4FB0
-	// osBlock defines the location of the first block of the TrustedOS on MMC.
-	osBlock = 0x5000
-	// appletBlock defines the location of the first block of the TrustedApplet on MMC.
-	appletBlock = 0x200000
-
-	operPlease = "🔷🔷🔷 🙋 OPERATOR: %s 🙏"
-)
-
 var (
-	template            = flag.String("template", "", fmt.Sprintf("One of the optional preconfigured templates (%v)", maps.Keys(release.Templates)))
+	template            = flag.String("template", "", fmt.Sprintf("One of the optional preconfigured templates (%v), or a template defined in --template_file.", maps.Keys(release.Templates)))
+	templateFile        = flag.String("template_file", "", "Path to a JSON file, or a directory of JSON files, defining additional templates. The files must be pinned with --template_sha256 or signed by --template_verifier.")
+	templateSHA256      = flag.String("template_sha256", "", "Comma separated list of hex encoded SHA256 hashes, one of which each --template_file must match.")
+	templateVerifier    = flag.String("template_verifier", "", "Verifier string for the key which each --template_file must be a signed note from.")
 	firmwareLogURL      = flag.String("firmware_log_url", "", "URL of the firmware transparency log to scan for firmware artefacts.")
 	firmwareLogOrigin   = flag.String("firmware_log_origin", "", "Origin string for the firmware transparency log.")
 	firmwareLogVerifier = flag.String("firmware_log_verifier", "", "Checkpoint verifier key for the firmware transparency log.")
 	binariesURL         = flag.String("binaries_url", "", "Base URL for fetching firmware artefacts referenced by FT log.")
+	firmwarePack        = flag.String("firmware_pack", "", "If set, the recovery image is taken from this firmware pack (see the export tool), and firmware is verified without access to the FT log.")
 
 	appletVerifier   = flag.String("applet_verifier", "", "Verifier key for the applet manifest.")
 	bootVerifier     = flag.String("boot_verifier", "", "Verifier key for the boot manifest.")
@@ -78,27 +70,37 @@ var (
 	osVerifier2      = flag.String("os_verifier_2", "", "Verifier key 2 for the OS manifest.")
 	recoveryVerifier = flag.String("recovery_verifier", "", "Verifier key for the recovery manifest.")
 
-	habTarget       = flag.String("hab_target", "", "Device type firmware must be targetting.")
-	blockDeviceGlob = flag.String("blockdevs", "/dev/disk/by-id/usb-F-Secure_USB_*", "Glob for plausible block devices where the armored witness could appear.")
+	habTarget        = flag.String("hab_target", "", "Device type firmware must be targetting.")
+	witnessVerifiers = flag.String("witness_verifiers", "", "Comma separated list of verifier strings for witnesses, at least --witness_threshold of which must have cosigned the FT log checkpoints which firmware is verified against.")
+	witnessThreshold = flag.Int("witness_threshold", 0, "Number of --witness_verifiers which must have cosigned each FT log checkpoint.")
+	habSRKHash       = flag.String("hab_srk_hash", "", "Hex encoded SRK hash of the HAB PKI for --hab_target. If set, firmware built with a different SRK hash is rejected.")
+	blockDeviceGlob  = flag.String("blockdevs", "/dev/disk/by-id/usb-F-Secure_USB_*", "Glob for plausible block devices where the armored witness could appear.")
+
+	mmcImage        = flag.String("mmc_image", "", "If set, the firmware on this MMC image file, or block device of a device already running the recovery image, is verified instead of booting a device.")
+	checkRegistry   = flag.Bool("check_registry", true, "If true, once its firmware is verified the device is booted, and the identities it reports are checked against the devices registry for --hab_target. The check is skipped for HAB targets other than ci and prod, which have no registry.")
+	registryTimeout = flag.Duration("registry_timeout", 5*time.Minute, "How long to wait for the device to boot and report its identity when checking it against the devices registry.")
+	checkUpdates    = flag.Bool("check_updates", true, "If true, the firmware installed on the device is compared with the releases in the FT log, to report whether it's up to date.")
+	checkUnused     = flag.Bool("check_unused", true, "If true, the parts of the MMC which aren't used by firmware are scanned for unexpected data.")
+	dumpMMCPath     = flag.String("dump_mmc", "", "If set, the entire MMC of the device is copied into a new image file at this path, and the firmware in the image is verified. The image can later be verified again with --mmc_image.")
+
+	auditLogPath = flag.String("audit_log", "", "Path of the hash-chained audit log recording everything done to each device. Defaults to a file within the user's config directory, shared with the provision tool.")
 
 	runAnyway = flag.Bool("run_anyway", false, "Let the user override bailing on any potential problems we've detected.")
+
+	outputFormat = flag.String("output_format", "text", "Format of the verification results, one of text or json. With json, a report of the verification of each component is written to --report_file, or stdout if that's not set.")
+	reportFile   = flag.String("report_file", "", "Path to write the verification report to when --output_format=json.")
+
+	operatorKind = flag.String("operator", "tty", fmt.Sprintf("How to interact with the operator, one of %v. The json operator writes events to stdout as JSON lines, and reads responses from stdin.", operator.Kinds))
 )
 
 func applyFlagTemplate(k string) {
-	t, ok := release.Templates[k]
-	if !ok {
-		klog.Exitf("No such template %q", k)
-	}
-	for f, v := range t {
-		if u := flag.Lookup(f); u == nil {
-			klog.Exitf("Internal error - template flag --%v unknown", f)
-		} else if u.Value.String() != "" {
-			klog.Exitf("Cannot set --template and --%s", f)
-		}
-		klog.Infof("Using template flag setting --%v=%v", f, v)
-		if err := flag.Set(f, v); err != nil {
-			klog.Exitf("Failed to set template flag --%v: %v", f, err)
-		}
+	src := release.TemplateSource{
+		Path:     *templateFile,
+		SHA256:   release.ParseHashes(*templateSHA256),
+		Verifier: *templateVerifier,
+	}
+	if err := src.Apply(k, release.FlagSet(flag.CommandLine), nil); err != nil {
+		klog.Exitf("Failed to apply template %q: %v", k, err)
 	}
 }
 
@@ -113,22 +115,43 @@ func main() {
 
 	ctx := context.Background()
 
+	if *mmcImage != "" && *dumpMMCPath != "" {
+		klog.Exit("Only one of --mmc_image and --dump_mmc may be set.")
+	}
 	if u, err := user.Current(); err != nil {
 		klog.Exitf("Failed to determine who I'm running as: %v", err)
-	} else if u.Uid != "0" {
+	} else if u.Uid != "0" && *mmcImage == "" {
 		klog.Warningf("⚠️ This tool probably needs to be run as root (e.g. via sudo), it's running as %q (UID %q); re-run with the --run_anyway flag if you know better.", u.Username, u.Uid)
 		if !*runAnyway {
 			klog.Exit("Bailing.")
 		}
 	}
 
-	if err := v.waitAndVerify(ctx); err != nil {
+	var err error
+	if v.audit, err = openAuditLog(); err != nil {
+		klog.Exitf("Failed to open audit log: %v", err)
+	}
+	err = v.waitAndVerify(ctx)
+	if *outputFormat == "json" {
+		if err := v.writeReport(err); err != nil {
+			klog.Errorf("❌ Failed to write verification report: %v", err)
+		}
+	}
+	if err != nil {
+		v.writeAudit(audit.EventFailed, map[string]string{"error": err.Error()})
 		klog.Exitf("❌ Failed to verify device: %v", err)
 	}
+	v.writeAudit(audit.EventVerified, v.fwDetails)
+	if *mmcImage != "" {
+		klog.Info("✅ MMC image verified OK!")
+		return
+	}
 	klog.Info("✅ Device verified OK!")
-	klog.Info("------------------------------------------------------------------------------------------------------------")
-	klog.Infof(operPlease, "please ensure boot switch is set to MMC (away from RJ45 socket), and then reboot device")
-	klog.Info("------------------------------------------------------------------------------------------------------------")
+	if *checkRegistry && hasRegistry() {
+		// The device has already been rebooted into its firmware.
+		return
+	}
+	v.op.Instruct("", operator.Instruction{Switch: operator.BootSwitchMMC, Action: "reboot device"})
 }
 
 // firmwares respresents the collection of firmware and related artefacts found
@@ -136,71 +159,165 @@ func main() {
 type firmwares struct {
 	// Bootloader holds the regular bootloader firmware bundle.
 	Bootloader firmware.Bundle
+	// BootloaderHABArea holds the MMC contents following the bootloader firmware, which
+	// should start with its HAB signature.
+	BootloaderHABArea []byte
 	// TrustedOS holds the trusted OS firmware bundle.
 	TrustedOS firmware.Bundle
 	// TrustedApplet holds the witness applet firmware bundle.
 	TrustedApplet firmware.Bundle
+
+	// used lists the extents of the MMC which hold the firmware and configs above.
+	used []extent
+	// bootloaderEnd is the MMC byte offset of the end of the bootloader firmware.
+	bootloaderEnd int64
 }
 
 // verifier is a struct which knows how to verify firmware transparency inclusion for
 // firmware on an armored witness device.
 type verifier struct {
-	logOrigin string
-	logV      note.Verifier
-
-	bootV     note.Verifier
-	appletV   note.Verifier
-	osV1      note.Verifier
-	osV2      note.Verifier
-	recoveryV note.Verifier
+	// pv holds the FT log and manifest verifiers, and is used to verify firmware packs.
+	pv *pack.Verifier
 
 	logBaseURL *url.URL
 	binBaseURL *url.URL
 
 	recovery firmware.Bundle
+
+	// op is used to interact with the operator.
+	op operator.Operator
+
+	// witnessPolicy must be satisfied by FT log checkpoints, if set.
+	witnessPolicy *policy.Policy
+
+	// pack is the verified firmware pack, and packCP its FT log checkpoint, or nil if
+	// we're not using one.
+	pack   *pack.Pack
+	packCP *log.Checkpoint
+	// releases holds every release in the FT log once it's been scanned, and fetchSession
+	// is the session it was scanned with.
+	releases     []fetcher.Release
+	fetchSession update.FetchSession
+
+	// audit is the audit log to record the verification in.
+	audit *audit.Log
+	// serial is the serial number of the device being verified, once known.
+	serial string
+	// fwDetails describes the firmware found on the device, for the audit log.
+	fwDetails map[string]string
+	// report records the results of verification, for --output_format=json.
+	report report
+	// image is the MMC image being verified, and imageSHA256 the hex encoded hash of
+	// its contents, if firmware is being verified from an image rather than a device.
+	image       string
+	imageSHA256 string
 }
 
 // fetchRecoveryFirmware returns a recovery image suitable for use on the armored witness,
 // and which has been verified to be present in the firmware transparency log.
 func (v *verifier) fetchRecoveryFirmware(ctx context.Context) error {
+	if *firmwarePack != "" {
+		return v.loadFirmwarePack(*firmwarePack)
+	}
+	rs, err := v.scanLog(ctx)
+	if err != nil {
+		return err
+	}
+	latest := fetcher.Latest(rs, ftlog.ComponentRecovery, v.pv.HABTarget)
+	if latest == nil {
+		return errors.New("no latest recovery available")
+	}
+
+	r, m, err := v.fetchSession.Fetch(ctx, latest.Index)
+	if err != nil {
+		return err
+	}
+	binFetcher := fetcher.BinaryFetcher(fetcher.New(v.binBaseURL))
+	if r.Firmware, r.HABSignature, err = binFetcher(ctx, *m); err != nil {
+		return fmt.Errorf("binFetcher(): %v", err)
+	}
+
+	if _, err := v.pv.VerifyBundle(ftlog.ComponentRecovery, *r); err != nil {
+		return err
+	}
+
+	v.recovery = *r
+	return nil
+}
+
+// scanLog returns every firmware release in the FT log, in log order.
+//
+// The log is only scanned once, for both the recovery image and the update check.
+func (v *verifier) scanLog(ctx context.Context) ([]fetcher.Release, error) {
+	if v.releases != nil {
+		return v.releases, nil
+	}
+	f, err := v.newUpdateFetcher(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if v.fetchSession, err = f.NewSession(ctx); err != nil {
+		return nil, fmt.Errorf("updateFetcher.NewSession: %v", err)
+	}
+	rs, cp, err := fetcher.Scan(ctx, v.fetchSession, v.pv.LogOrigin, v.pv.LogVerifier)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.witnessPolicy.Check(cp); err != nil {
+		return nil, fmt.Errorf("log checkpoint: %v", err)
+	}
+	v.releases = rs
+	return rs, nil
+}
+
+// newUpdateFetcher returns a fetcher for the firmware in the FT log.
+func (v *verifier) newUpdateFetcher(ctx context.Context) (*update.Fetcher, error) {
 	logFetcher := fetcher.New(v.logBaseURL)
 	binFetcher := fetcher.BinaryFetcher(fetcher.New(v.binBaseURL))
+	mv := v.pv.ManifestVerifiers
 	updateFetcher, err := update.NewFetcher(ctx,
 		update.FetcherOpts{
 			LogFetcher:       logFetcher,
-			LogOrigin:        v.logOrigin,
-			LogVerifier:      v.logV,
+			LogOrigin:        v.pv.LogOrigin,
+			LogVerifier:      v.pv.LogVerifier,
 			BinaryFetcher:    binFetcher,
-			AppletVerifier:   v.appletV,
-			BootVerifier:     v.bootV,
-			OSVerifiers:      [2]note.Verifier{v.osV1, v.osV2},
-			RecoveryVerifier: v.recoveryV,
-			HABTarget:        *habTarget,
+			AppletVerifier:   mv[ftlog.ComponentApplet][0],
+			BootVerifier:     mv[ftlog.ComponentBoot][0],
+			OSVerifiers:      [2]note.Verifier{mv[ftlog.ComponentOS][0], mv[ftlog.ComponentOS][1]},
+			RecoveryVerifier: mv[ftlog.ComponentRecovery][0],
+			HABTarget:        v.pv.HABTarget,
 		})
 	if err != nil {
-		return fmt.Errorf("update.NewFetcher: %v", err)
+		return nil, fmt.Errorf("update.NewFetcher: %v", err)
 	}
+	return updateFetcher, nil
+}
 
-	if err := updateFetcher.Scan(ctx); err != nil {
-		return fmt.Errorf("updateFetcher.Scan: %v", err)
+// loadFirmwarePack reads the firmware pack at the given path, verifies its contents, and takes
+// the recovery image and the FT log checkpoint to verify against from it.
+func (v *verifier) loadFirmwarePack(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open firmware pack: %v", err)
 	}
-
-	r, err := updateFetcher.GetRecovery(ctx)
+	defer func() {
+		if err := f.Close(); err != nil {
+			klog.Errorf("Error closing %v: %v", path, err)
+		}
+	}()
+	p, err := pack.Read(f)
 	if err != nil {
-		return fmt.Errorf("updateFetcher.GetRecovery: %v", err)
+		return err
 	}
-
-	bv := firmware.BundleVerifier{
-		LogOrigin:         v.logOrigin,
-		LogVerifer:        v.logV,
-		ManifestVerifiers: []note.Verifier{v.recoveryV},
+	if _, err := v.pv.Verify(p); err != nil {
+		return fmt.Errorf("firmware pack failed verification: %v", err)
 	}
-
-	if _, err := bv.Verify(r); err != nil {
-		return err
+	if v.packCP, err = v.pv.Checkpoint(p); err != nil {
+		return fmt.Errorf("firmware pack checkpoint: %v", err)
 	}
-
-	v.recovery = r
+	v.pack = p
+	v.recovery = p.Recovery
+	klog.Infof("✅ Firmware pack verified, checkpoint @ %d", v.packCP.Size)
 	return nil
 }
 
@@ -210,14 +327,19 @@ func (v *verifier) fetchRecoveryFirmware(ctx context.Context) error {
 // is signed by the correct key(s), the manifests are present in the firmware transparency log,
 // and that the bundled log checkpoint is consistent with the current view of the log from the
 // workstation running this command.
+//
+// If --dump_mmc is set, the whole MMC is first copied to an image file, and the firmware is
+// extracted from the image instead. If --mmc_image is set, no device is booted at all, and
+// the firmware is extracted from the given image.
 func (v *verifier) waitAndVerify(ctx context.Context) error {
+	if *mmcImage != "" {
+		return v.verifyImage(ctx, *mmcImage)
+	}
 	if err := v.fetchRecoveryFirmware(ctx); err != nil {
-		klog.Exitf("Failed to fetch device recovery image: %v", err)
+		return fmt.Errorf("failed to fetch device recovery image: %v", err)
 	}
 	klog.Info("Successfully fetched and verified recovery image")
-	klog.Info("------------------------------------------------------------------------------------------------------------")
-	klog.Infof(operPlease, "please ensure boot switch is set to USB (towards RJ45 socket), and then connect device")
-	klog.Info("------------------------------------------------------------------------------------------------------------")
+	v.op.Instruct("", operator.Instruction{Switch: operator.BootSwitchUSB, Action: "connect device"})
 
 	recoveryHAB := append(v.recovery.Firmware, v.recovery.HABSignature...)
 	klog.Infof("Recovery firmware is %d bytes + %d bytes HAB signature", len(v.recovery.Firmware), len(v.recovery.HABSignature))
@@ -229,8 +351,55 @@ func (v *verifier) waitAndVerify(ctx context.Context) error {
 	}
 	klog.Infof("✅ Detected device %q", target.DeviceInfo.Path)
 	klog.Infof("✅ Detected blockdevice %v", bDev)
+	v.serial = device.SerialFromBlockDevice(bDev)
+	v.report.Serial = v.serial
+	v.writeAudit(audit.EventDetected, nil)
 
+	if *dumpMMCPath != "" {
+		h, err := dumpMMC(bDev, *dumpMMCPath)
+		if err != nil {
+			return fmt.Errorf("failed to dump MMC: %v", err)
+		}
+		klog.Infof("✅ MMC dumped to %s, SHA256 %s", *dumpMMCPath, h)
+		v.image, v.imageSHA256 = *dumpMMCPath, h
+		// Verify what was captured, so that the results hold for the image too.
+		bDev = *dumpMMCPath
+	}
+	if err := v.verifyMMC(ctx, bDev); err != nil {
+		return err
+	}
+	if *checkRegistry {
+		return v.checkRegistry(ctx)
+	}
+	return nil
+}
+
+// verifyImage verifies the firmware on an MMC image file, or the block device of a device
+// which is already running the recovery image, without needing to boot a device.
+func (v *verifier) verifyImage(ctx context.Context, path string) error {
+	if *firmwarePack != "" {
+		if err := v.loadFirmwarePack(*firmwarePack); err != nil {
+			return err
+		}
+	}
+	h, err := hashFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to hash MMC image: %v", err)
+	}
+	klog.Infof("Verifying MMC image %s, SHA256 %s", path, h)
+	v.image, v.imageSHA256 = path, h
+	// This only finds a serial number for block devices named after the device.
+	v.serial = device.SerialFromBlockDevice(path)
+	v.report.Serial = v.serial
+	v.writeAudit(audit.EventDetected, map[string]string{"mmc_image": path})
+	return v.verifyMMC(ctx, path)
+}
+
+// verifyMMC extracts the bootloader, trusted OS, and trusted applet firmware from the MMC
+// block device or image at bDev, and verifies them.
+func (v *verifier) verifyMMC(ctx context.Context, bDev string) error {
 	var fw *firmwares
+	var err error
 	// There appears to be a race on Linux between the device file appearing and being able to open and use it.
 	// Give it a couple of tries just in case:
 	for i := 0; i < 2; i++ {
@@ -248,73 +417,196 @@ func (v *verifier) waitAndVerify(ctx context.Context) error {
 
 	}
 
-	if err := v.verifyFirmwares(ctx, *fw); err != nil {
-		return err
+	v.fwDetails = map[string]string{}
+	if v.image != "" {
+		v.fwDetails["mmc_image"] = v.image
+		v.fwDetails["mmc_image_sha256"] = v.imageSHA256
+		v.report.MMCImage = v.image
+		v.report.MMCImageSHA256 = v.imageSHA256
+	}
+	for name, b := range map[string]firmware.Bundle{"boot": fw.Bootloader, "os": fw.TrustedOS, "applet": fw.TrustedApplet} {
+		h := sha256.Sum256(b.Firmware)
+		v.fwDetails[name+"_index"] = fmt.Sprintf("%d", b.Index)
+		v.fwDetails[name+"_sha256"] = hex.EncodeToString(h[:])
 	}
 
-	return nil
+	verifyErr := v.verifyFirmwares(ctx, fw)
+	if *checkUpdates {
+		if err := v.checkUpdates(ctx); err != nil {
+			// Whether or not the firmware is current, it's still authentic.
+			klog.Warningf("⚠️  Failed to check for newer releases: %v", err)
+		}
+	}
+	if *checkUnused {
+		if err := v.checkUnused(bDev, fw); err != nil {
+			verifyErr = errors.Join(verifyErr, fmt.Errorf("failed to scan unused MMC: %v", err))
+		}
+	} else {
+		v.report.UnusedScan = resultSkipped
+	}
+	return verifyErr
 }
 
-// verifyFirmwares performs the firmware transparency verification of the firmware bundles
-func (v *verifier) verifyFirmwares(ctx context.Context, fw firmwares) error {
-	logFetcher := fetcher.New(v.logBaseURL)
-	lst, err := client.NewLogStateTracker(ctx, logFetcher, rfc6962.DefaultHasher, nil, v.logV, v.logOrigin, client.UnilateralConsensus(logFetcher))
+// openAuditLog opens the audit log given by the --audit_log flag, or the default audit
+// log shared with the provision tool if the flag isn't set.
+func openAuditLog() (*audit.Log, error) {
+	p := *auditLogPath
+	if p == "" {
+		var err error
+		if p, err = audit.DefaultPath(); err != nil {
+			return nil, fmt.Errorf("unable to determine default audit log location, use --audit_log: %v", err)
+		}
+	}
+	l, err := audit.Open(p, "verify")
 	if err != nil {
-		return fmt.Errorf("failed to create LogStateTracker: %v", err)
+		return nil, err
+	}
+	klog.Infof("Recording audit log in %s", p)
+	return l, nil
+}
+
+// writeAudit appends an entry about the device being verified to the audit log.
+func (v *verifier) writeAudit(event string, details map[string]string) {
+	if err := v.audit.Append(v.serial, event, details); err != nil {
+		klog.Errorf("❌ Failed to record %s event in audit log: %v", event, err)
+	}
+}
+
+// verifyFirmwares performs the firmware transparency verification of the firmware bundles
+//
+// The bootloader HAB signature found on the MMC is stored in fw, if it matches the manifest.
+func (v *verifier) verifyFirmwares(ctx context.Context, fw *firmwares) error {
+	var lst client.LogStateTracker
+	if v.packCP == nil {
+		logFetcher := fetcher.New(v.logBaseURL)
+		var err error
+		lst, err = client.NewLogStateTracker(ctx, logFetcher, rfc6962.DefaultHasher, nil, v.pv.LogVerifier, v.pv.LogOrigin, v.witnessPolicy.Consensus(logFetcher))
+		if err != nil {
+			return fmt.Errorf("failed to create LogStateTracker: %v", err)
+		}
 	}
 
 	errs := []error{}
 
-	for _, p := range []struct {
+	parts := []struct {
 		name       string
 		bundle     firmware.Bundle
 		manifestVs []note.Verifier
 	}{
-		{name: "Bootloader", bundle: fw.Bootloader, manifestVs: []note.Verifier{v.bootV}},
-		{name: "TrustedOS", bundle: fw.TrustedOS, manifestVs: []note.Verifier{v.osV1, v.osV2}},
-		{name: "TrustedApplet", bundle: fw.TrustedApplet, manifestVs: []note.Verifier{v.appletV}},
-	} {
-		// First verify that the stored proof bundle is self-consistent:
-		bv := firmware.BundleVerifier{
-			LogOrigin:         v.logOrigin,
-			LogVerifer:        v.logV,
-			ManifestVerifiers: p.manifestVs,
+		{name: "Bootloader", bundle: fw.Bootloader, manifestVs: v.pv.ManifestVerifiers[ftlog.ComponentBoot]},
+		{name: "TrustedOS", bundle: fw.TrustedOS, manifestVs: v.pv.ManifestVerifiers[ftlog.ComponentOS]},
+		{name: "TrustedApplet", bundle: fw.TrustedApplet, manifestVs: v.pv.ManifestVerifiers[ftlog.ComponentApplet]},
+	}
+	for i, p := range parts {
+		v.op.Progress("", fmt.Sprintf("verify %s", p.name), i+1, len(parts))
+		r := &componentReport{
+			Name:               p.name,
+			LogIndex:           p.bundle.Index,
+			BundleVerification: resultSkipped,
+			ConsistencyProof:   resultSkipped,
 		}
-		extractedFWHash := sha256.Sum256(p.bundle.Firmware)
-		klog.V(1).Infof("%s extracted firmware has base64 hash: %s", p.name, base64.StdEncoding.EncodeToString(extractedFWHash[:]))
-		klog.V(1).Infof("%s Manifest:\n%s", p.name, p.bundle.Manifest)
-		if _, err := bv.Verify(p.bundle); err != nil {
-			klog.Infof("  ❌ %s: %v", p.name, err)
+		v.report.Components = append(v.report.Components, r)
+		if err := v.verifyBundle(ctx, &lst, p.name, p.bundle, p.manifestVs, r); err != nil {
+			r.Error = err.Error()
 			errs = append(errs, fmt.Errorf("failed to verify %s: %v", p.name, err))
-			continue
-		}
-		klog.Infof("  ✅ %s: proof bundle is self-consistent ", p.name)
-
-		// Now verify that the checkpoint used in the proofbundle is consitent with our
-		// view of the log:
-		fwCP, _, _, err := log.ParseCheckpoint(p.bundle.Checkpoint, v.logOrigin, v.logV)
-		if err != nil {
-			return fmt.Errorf("failed to parse checkpoint from %s: %v", p.name, err)
 		}
-		if fwCP.Size > lst.LatestConsistent.Size {
-			if _, _, _, err := lst.Update(ctx); err != nil {
-				return fmt.Errorf("failed to update LogStateTracker: %v", err)
+		if p.name == "Bootloader" {
+			sig, err := v.verifyHABSignature(ctx, p.name, fw.Bootloader, fw.BootloaderHABArea, r)
+			if err != nil {
+				if r.Error != "" {
+					r.Error += "; "
+				}
+				r.Error += err.Error()
+				errs = append(errs, fmt.Errorf("failed to verify %s HAB signature: %v", p.name, err))
 			}
+			fw.Bootloader.HABSignature = sig
 		}
+	}
+	if v.packCP == nil {
+		v.report.LogSize = lst.LatestConsistent.Size
+	} else {
+		v.report.LogSize = v.packCP.Size
+	}
 
-		cp, err := lst.ProofBuilder.ConsistencyProof(ctx, fwCP.Size, lst.LatestConsistent.Size)
-		if err != nil {
-			return fmt.Errorf("failed to build consistency proof for %s checkpoint: %v", p.name, err)
+	return errors.Join(errs...)
+}
+
+// verifyBundle verifies a single firmware bundle, recording the results in r.
+func (v *verifier) verifyBundle(ctx context.Context, lst *client.LogStateTracker, name string, b firmware.Bundle, manifestVs []note.Verifier, r *componentReport) error {
+	// First verify that the stored proof bundle is self-consistent:
+	bv := firmware.BundleVerifier{
+		LogOrigin:         v.pv.LogOrigin,
+		LogVerifer:        v.pv.LogVerifier,
+		ManifestVerifiers: manifestVs,
+	}
+	extractedFWHash := sha256.Sum256(b.Firmware)
+	r.FirmwareSHA256 = hex.EncodeToString(extractedFWHash[:])
+	klog.V(1).Infof("%s extracted firmware has base64 hash: %s", name, base64.StdEncoding.EncodeToString(extractedFWHash[:]))
+	klog.V(1).Infof("%s Manifest:\n%s", name, b.Manifest)
+	m, err := bv.Verify(b)
+	if err != nil {
+		klog.Infof("  ❌ %s: %v", name, err)
+		r.BundleVerification = resultFailed
+		return err
+	}
+	r.Manifest = m
+	if err := v.witnessPolicy.Check(b.Checkpoint); err != nil {
+		klog.Infof("  ❌ %s: proof bundle checkpoint: %v", name, err)
+		r.WitnessCosignatures = resultFailed
+		r.BundleVerification = resultFailed
+		return fmt.Errorf("proof bundle checkpoint: %v", err)
+	}
+	r.WitnessCosignatures = resultOK
+	klog.Infof("  ✅ %s: proof bundle checkpoint satisfies witness policy: %s", name, v.witnessPolicy)
+	if *habSRKHash != "" {
+		if err := release.CheckSRKHash(m, *habSRKHash); err != nil {
+			klog.Infof("  ❌ %s: %v", name, err)
+			r.BundleVerification = resultFailed
+			return err
+		}
+		klog.Infof("  ✅ %s: built for expected SRK hash", name)
+	}
+	r.BundleVerification = resultOK
+	klog.Infof("  ✅ %s: proof bundle is self-consistent ", name)
+
+	// Now verify that the checkpoint used in the proofbundle is consitent with our
+	// view of the log:
+	fwCP, _, _, err := log.ParseCheckpoint(b.Checkpoint, v.pv.LogOrigin, v.pv.LogVerifier)
+	if err != nil {
+		return fmt.Errorf("failed to parse checkpoint: %v", err)
+	}
+	r.CheckpointSize = fwCP.Size
+	if v.pack != nil {
+		// We have no access to the log, so check consistency using the firmware pack's
+		// leaf hashes instead.
+		if err := v.pack.CheckConsistency(fwCP.Size, fwCP.Hash); err != nil {
+			klog.Infof("  ❌ %s: %v", name, err)
+			r.ConsistencyProof = resultFailed
+			return fmt.Errorf("checkpoint is not consistent with firmware pack: %v", err)
 		}
-		if err := proof.VerifyConsistency(rfc6962.DefaultHasher, fwCP.Size, lst.LatestConsistent.Size, cp, fwCP.Hash, lst.LatestConsistent.Hash); err != nil {
-			klog.Infof("%s proof bundle checkpoint:\n%s", p.name, p.bundle.Checkpoint)
-			klog.Infof("%s my checkpoint:\n%s", p.name, lst.LatestConsistentRaw)
-			return fmt.Errorf("invalid consistency proof for %s checkpoint: %v", p.name, err)
+		r.ConsistencyProof = resultOK
+		klog.Infof("  ✅ %s: proof bundle checkpoint(@%d) is consistent with firmware pack checkpoint(@%d)", name, fwCP.Size, v.packCP.Size)
+		return nil
+	}
+	if fwCP.Size > lst.LatestConsistent.Size {
+		if _, _, _, err := lst.Update(ctx); err != nil {
+			return fmt.Errorf("failed to update LogStateTracker: %v", err)
 		}
-		klog.Infof("  ✅ %s: proof bundle checkpoint(@%d) is consistent with current view of log(@%d)", p.name, fwCP.Size, lst.LatestConsistent.Size)
 	}
 
-	return errors.Join(errs...)
+	cp, err := lst.ProofBuilder.ConsistencyProof(ctx, fwCP.Size, lst.LatestConsistent.Size)
+	if err != nil {
+		return fmt.Errorf("failed to build consistency proof for checkpoint: %v", err)
+	}
+	if err := proof.VerifyConsistency(rfc6962.DefaultHasher, fwCP.Size, lst.LatestConsistent.Size, cp, fwCP.Hash, lst.LatestConsistent.Hash); err != nil {
+		klog.Infof("%s proof bundle checkpoint:\n%s", name, b.Checkpoint)
+		klog.Infof("%s my checkpoint:\n%s", name, lst.LatestConsistentRaw)
+		r.ConsistencyProof = resultFailed
+		return fmt.Errorf("invalid consistency proof for checkpoint: %v", err)
+	}
+	r.ConsistencyProof = resultOK
+	klog.Infof("  ✅ %s: proof bundle checkpoint(@%d) is consistent with current view of log(@%d)", name, fwCP.Size, lst.LatestConsistent.Size)
+	return nil
 }
 
 // extractFirmware attempts to read bootloader, os, and applet firmware and
@@ -329,22 +621,31 @@ func extractFirmware(dev string) (*firmwares, error) {
 			klog.Errorf("Error closing %v: %v", dev, err)
 		}
 	}()
-	bootloader, err := readFirmware(f, bootloaderConfigBlock)
+	bootloader, bootExts, err := readFirmware(f, layout.BootloaderConfig.Block, layout.Bootloader, "bootloader")
 	if err != nil {
 		return nil, fmt.Errorf("failed to read bootloader IMX: %v", err)
 	}
-	os, err := readFirmware(f, osBlock)
+	// The provision tool writes the HAB signature immediately after the bootloader.
+	bootloaderEnd := bootExts[1].end()
+	habArea, err := readHABArea(f, bootloaderEnd)
+	if err != nil {
+		return nil, err
+	}
+	os, osExts, err := readFirmware(f, layout.OS.Block, layout.OS, "TrustedOS")
 	if err != nil {
 		return nil, fmt.Errorf("failed to read OS: %v", err)
 	}
-	applet, err := readFirmware(f, appletBlock)
+	applet, appletExts, err := readFirmware(f, layout.Applet.Block, layout.Applet, "TrustedApplet")
 	if err != nil {
 		return nil, fmt.Errorf("failed to read Applet: %v", err)
 	}
 	fw := &firmwares{
-		Bootloader:    bootloader,
-		TrustedApplet: applet,
-		TrustedOS:     os,
+		Bootloader:        bootloader,
+		BootloaderHABArea: habArea,
+		TrustedApplet:     applet,
+		TrustedOS:         os,
+		used:              slices.Concat(bootExts, osExts, appletExts),
+		bootloaderEnd:     bootloaderEnd,
 	}
 	return fw, nil
 }
@@ -353,11 +654,18 @@ func extractFirmware(dev string) (*firmwares, error) {
 // and uses the information it contains to locate and read the corresponding firmware
 // image too.
 //
-// A firmware bundle is returned with image + proof bundle information.
-func readFirmware(f *os.File, cfgBlock int64) (firmware.Bundle, error) {
-	cfg, err := readConfig(f, cfgBlock)
+// The config is untrusted, so the firmware location it specifies must lie entirely
+// within fwRegion.
+//
+// A firmware bundle is returned with image + proof bundle information, along with the
+// extents of the MMC holding the config and the firmware, in that order, named after name.
+func readFirmware(f *os.File, cfgBlock int64, fwRegion layout.Region, name string) (firmware.Bundle, []extent, error) {
+	cfg, cfgLen, err := layout.ReadConfig(f, cfgBlock)
 	if err != nil {
-		return firmware.Bundle{}, err
+		return firmware.Bundle{}, nil, err
+	}
+	if err := fwRegion.Contains(cfg.Offset, cfg.Size); err != nil {
+		return firmware.Bundle{}, nil, fmt.Errorf("config at block 0x%x has invalid firmware location: %v", cfgBlock, err)
 	}
 
 	fw := firmware.Bundle{
@@ -374,59 +682,45 @@ func readFirmware(f *os.File, cfgBlock int64) (firmware.Bundle, error) {
 	}
 	klog.Infof("Reading 0x%x bytes of firmware from MMC byte offset 0x%x", cfg.Size, cfg.Offset)
 	if _, err := f.ReadAt(fw.Firmware, cfg.Offset); err != nil {
-		return firmware.Bundle{}, fmt.Errorf("failed to read firmware data: %v", err)
+		return firmware.Bundle{}, nil, fmt.Errorf("failed to read firmware data: %v", err)
 	}
-	return fw, nil
-}
-
-// readConfig attempts to read and parse a GOB encoded Config structure from the
-// MMC starting at the specificed block.
-//
-// This structure holds the location on MMC at which the corresponding firmware
-// image can be found, as well as the FT proofbundle for that image.
-func readConfig(f *os.File, cfgBlock int64) (*config.Config, error) {
-	buf := make([]byte, config.MaxLength)
-	if _, err := f.ReadAt(buf, cfgBlock*mmcBlockSize); err != nil {
-		return nil, fmt.Errorf("failed to read config region @ block %d: %v", cfgBlock, err)
-	}
-
-	cfg := &config.Config{}
-	if err := gob.NewDecoder(bytes.NewReader(buf)).Decode(cfg); err != nil {
-		return nil, fmt.Errorf("failed to decode config: %v", err)
+	exts := []extent{
+		{name: name + " config", offset: cfgBlock * layout.BlockSize, size: cfgLen},
+		{name: name, offset: cfg.Offset, size: cfg.Size},
 	}
-
-	return cfg, nil
+	return fw, exts, nil
 }
 
 // verifierFromFlags creates a new verifier from information passed in through flags.
 func verifierFromFlags() verifier {
 	var err error
-	v := verifier{
-		logOrigin: *firmwareLogOrigin,
-	}
-	v.logV, err = note.NewVerifier(*firmwareLogVerifier)
-	if err != nil {
-		klog.Exitf("Invalid firmware log verifier: %v", err)
-	}
-	v.appletV, err = note.NewVerifier(*appletVerifier)
+	v := verifier{}
+	v.witnessPolicy, err = policy.FromFlags(*witnessVerifiers, *witnessThreshold)
 	if err != nil {
-		klog.Exitf("Invalid applet verifier: %v", err)
+		klog.Exitf("Invalid witness policy: %v", err)
 	}
-	v.bootV, err = note.NewVerifier(*bootVerifier)
+	v.pv, err = pack.VerifierFromFlags(release.FlagSet(flag.CommandLine), v.witnessPolicy)
 	if err != nil {
-		klog.Exitf("Invalid boot verifier: %v", err)
+		klog.Exitf("Invalid flags: %v", err)
 	}
-	v.osV1, err = note.NewVerifier(*osVerifier1)
+	v.op, err = operator.New(*operatorKind)
 	if err != nil {
-		klog.Exitf("Invalid OS verifier 1: %v", err)
+		klog.Exitf("Invalid --operator: %v", err)
 	}
-	v.osV2, err = note.NewVerifier(*osVerifier2)
-	if err != nil {
-		klog.Exitf("Invalid OS verifier 2: %v", err)
-	}
-	v.recoveryV, err = note.NewVerifier(*recoveryVerifier)
-	if err != nil {
-		klog.Exitf("Invalid recovery verifier: %v", err)
+	switch *outputFormat {
+	case "text":
+	case "json":
+		if *reportFile == "" && *operatorKind == "json" {
+			klog.Exit("--report_file must be set when using --output_format=json with --operator=json, since the operator uses stdout.")
+		}
+	default:
+		klog.Exitf("Invalid --output_format %q, must be one of text or json", *outputFormat)
+	}
+	v.report = report{
+		LogOrigin:     v.pv.LogOrigin,
+		WitnessPolicy: v.witnessPolicy.String(),
+		HABTarget:     *habTarget,
+		FirmwarePack:  *firmwarePack != "",
 	}
 
 	v.logBaseURL, err = url.Parse(*firmwareLogURL)
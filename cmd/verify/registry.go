@@ -0,0 +1,213 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/transparency-dev/armored-witness-os/api"
+	"github.com/transparency-dev/armored-witness/devices"
+	"github.com/transparency-dev/armored-witness/internal/device"
+	"github.com/transparency-dev/armored-witness/internal/operator"
+	"k8s.io/klog/v2"
+)
+
+// registryReport is the result of checking a device against the devices registry.
+type registryReport struct {
+	// Environment is the name of the registry the device was looked up in, e.g. prod.
+	Environment string `json:"environment"`
+	// Checks holds the result of each check made.
+	Checks []registryCheck `json:"checks"`
+}
+
+// registryCheck is the result of a single check against the devices registry.
+type registryCheck struct {
+	Name   string `json:"name"`
+	Result string `json:"result"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// registries are the device registries, keyed by the HAB target of their devices.
+var registries = map[string]map[string]devices.Device{
+	"ci":   devices.CI,
+	"prod": devices.Prod,
+}
+
+// hasRegistry returns true if there's a devices registry for --hab_target.
+func hasRegistry() bool {
+	_, ok := registries[*habTarget]
+	return ok
+}
+
+// checkRegistry has the operator boot the device into its installed firmware, and then
+// checks that the identities it reports match those recorded for it in the devices registry
+// for --hab_target.
+//
+// This shows that the device is the one which was registered when it was provisioned,
+// rather than merely one running authentic firmware.
+//
+// There are only registries for the built-in release environments, so for any other
+// --hab_target the check is recorded as skipped, and the device isn't booted.
+func (v *verifier) checkRegistry(ctx context.Context) error {
+	v.report.Registry = &registryReport{Environment: *habTarget}
+	reg, ok := registries[*habTarget]
+	if !ok {
+		detail := fmt.Sprintf("no devices registry for HAB target %q", *habTarget)
+		v.report.Registry.Checks = []registryCheck{{Name: "registry", Result: resultSkipped, Detail: detail}}
+		v.fwDetails["registry"] = resultSkipped
+		klog.Warningf("  ⚠️  Registry check skipped, %s", detail)
+		return nil
+	}
+
+	v.op.Instruct("", operator.Instruction{Switch: operator.BootSwitchMMC, Action: "reboot device"})
+	ctx, cancel := context.WithTimeout(ctx, *registryTimeout)
+	defer cancel()
+	s, err := waitForStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch status from device: %v", err)
+	}
+
+	checks := registryChecks(s, v.serial, reg)
+	v.report.Registry.Checks = checks
+	errs := []error{}
+	for _, c := range checks {
+		switch c.Result {
+		case resultOK:
+			klog.Infof("  ✅ Registry %s: %s", c.Name, c.Detail)
+		case resultSkipped:
+			klog.Warningf("  ⚠️  Registry %s: skipped, %s", c.Name, c.Detail)
+		default:
+			klog.Infof("  ❌ Registry %s: %s", c.Name, c.Detail)
+			errs = append(errs, fmt.Errorf("%s: %s", c.Name, c.Detail))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		v.fwDetails["registry"] = resultFailed
+		return fmt.Errorf("device does not match the %s devices registry: %v", *habTarget, err)
+	}
+	v.fwDetails["registry"] = resultOK
+	return nil
+}
+
+// waitForStatus waits for a device running the witness to appear, and returns its status
+// once the applet reports a witness identity.
+func waitForStatus(ctx context.Context) (*api.Status, error) {
+	path, dev, err := device.WaitForU2F(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	defer dev.Close()
+	klog.Infof("✅ Detected device %q", path)
+	klog.Info("Waiting for the witness applet to report its identity...")
+	for {
+		s, err := device.WitnessStatus(dev)
+		if err == nil && s.GetWitness().GetIdentity() != "" {
+			return s, nil
+		}
+		// The applet may still be starting up.
+		klog.V(1).Infof("Witness status not yet available: %v", err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// registryChecks compares the status s reported by a device with its entry in reg.
+//
+// serial is the serial number of the device when it booted into recovery mode, and the
+// check fails if it's not known, as the device reporting s can't then be shown to be the
+// one whose firmware was verified.
+func registryChecks(s *api.Status, serial string, reg map[string]devices.Device) []registryCheck {
+	fail := func(name, detail string, args ...any) registryCheck {
+		return registryCheck{Name: name, Result: resultFailed, Detail: fmt.Sprintf(detail, args...)}
+	}
+	ok := func(name, detail string) registryCheck {
+		return registryCheck{Name: name, Result: resultOK, Detail: detail}
+	}
+	skip := func(name, detail string) registryCheck {
+		return registryCheck{Name: name, Result: resultSkipped, Detail: detail}
+	}
+
+	checks := []registryCheck{}
+	switch {
+	case serial == "":
+		checks = append(checks, fail("serial", "device reports %s, but the serial number of the verified device is unknown", s.Serial))
+	case !strings.EqualFold(s.Serial, serial):
+		checks = append(checks, fail("serial", "device reports %s, but %s was verified", s.Serial, serial))
+	default:
+		checks = append(checks, ok("serial", s.Serial))
+	}
+
+	if s.HAB {
+		checks = append(checks, ok("HAB", "fused"))
+	} else {
+		checks = append(checks, fail("HAB", "device is not fused"))
+	}
+	switch {
+	case *habSRKHash == "":
+		checks = append(checks, skip("SRK hash", "no --hab_srk_hash set"))
+	case !strings.EqualFold(s.SRKHash, *habSRKHash):
+		checks = append(checks, fail("SRK hash", "device reports %q, want %q", s.SRKHash, *habSRKHash))
+	default:
+		checks = append(checks, ok("SRK hash", s.SRKHash))
+	}
+
+	d, found := devices.ForSerial(reg, s.Serial)
+	if !found {
+		return append(checks, fail("registered", "no device with serial %s in registry", s.Serial))
+	}
+	checks = append(checks, ok("registered", d.ID))
+
+	w := s.GetWitness()
+	if w.GetIDAttestPublicKey() != d.AttestPubkey {
+		checks = append(checks, fail("attestation key", "device reports %q, registered %q", w.GetIDAttestPublicKey(), d.AttestPubkey))
+	} else {
+		checks = append(checks, ok("attestation key", d.AttestPubkey))
+	}
+	if w.GetIdentity() != d.WitnessPubkey {
+		checks = append(checks, fail("witness identity", "device reports %q (identity counter %d), registered %q", w.GetIdentity(), s.IdentityCounter, d.WitnessPubkey))
+	} else {
+		checks = append(checks, ok("witness identity", d.WitnessPubkey))
+	}
+
+	// The bastion ID is only reported in attested form, so the attestations must be checked
+	// in order to compare it.
+	a, err := devices.Attested(s.Serial, w.GetIDAttestPublicKey(), w.GetAttestedID(), w.GetAttestedBastionID())
+	switch {
+	case err != nil:
+		checks = append(checks, fail("attestations", "%v", err))
+	case a.WitnessPubkey != d.WitnessPubkey:
+		checks = append(checks, fail("attestations", "device attests to witness identity %q, registered %q", a.WitnessPubkey, d.WitnessPubkey))
+	default:
+		checks = append(checks, ok("attestations", "valid"))
+	}
+	switch {
+	case d.BastionID == "":
+		checks = append(checks, skip("bastion ID", "no bastion ID registered"))
+	case err != nil:
+		checks = append(checks, fail("bastion ID", "attestations are invalid"))
+	case a.BastionID != d.BastionID:
+		checks = append(checks, fail("bastion ID", "device attests to %q, registered %q", a.BastionID, d.BastionID))
+	default:
+		checks = append(checks, ok("bastion ID", d.BastionID))
+	}
+	return checks
+}
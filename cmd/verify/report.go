@@ -0,0 +1,128 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/transparency-dev/armored-witness-common/release/firmware/ftlog"
+	"k8s.io/klog/v2"
+)
+
+// Results of the individual checks made on each component.
+const (
+	resultOK      = "ok"
+	resultFailed  = "failed"
+	resultSkipped = "skipped"
+	// resultUncheckedOffline means the check couldn't be made without access to the FT log.
+	resultUncheckedOffline = "unchecked_offline"
+	// resultFound means the scan of unused MMC areas found data.
+	resultFound = "found"
+)
+
+// report is the machine readable result of verifying a device.
+type report struct {
+	// Serial is the serial number of the device, if it was detected.
+	Serial string `json:"serial,omitempty"`
+	// Time is when verification finished.
+	Time time.Time `json:"time"`
+	// LogOrigin is the origin of the FT log the firmware was verified against.
+	LogOrigin string `json:"log_origin"`
+	// LogSize is the size of the view of the FT log which checkpoints were checked for
+	// consistency with; the firmware pack checkpoint when verifying offline.
+	LogSize uint64 `json:"log_size"`
+	// WitnessPolicy describes the witness cosignatures checkpoints were required to have.
+	WitnessPolicy string `json:"witness_policy"`
+	// HABTarget is the release environment the firmware was expected to target.
+	HABTarget string `json:"hab_target,omitempty"`
+	// MMCImage is the MMC image file the firmware was verified from, if any, and
+	// MMCImageSHA256 the hex encoded SHA256 hash of its contents.
+	MMCImage       string `json:"mmc_image,omitempty"`
+	MMCImageSHA256 string `json:"mmc_image_sha256,omitempty"`
+	// FirmwarePack is true if the device was verified offline using a firmware pack.
+	FirmwarePack bool `json:"firmware_pack"`
+	// Components holds the results for each firmware component found on the device.
+	Components []*componentReport `json:"components"`
+	// UnusedScan is the result of scanning the unused parts of the MMC for data, and
+	// UnusedData lists the data found.
+	UnusedScan string       `json:"unused_scan"`
+	UnusedData []unusedData `json:"unused_data,omitempty"`
+	// Registry holds the results of checking the device against the devices registry, if
+	// that was done.
+	Registry *registryReport `json:"registry,omitempty"`
+	// OK is true if the device passed verification.
+	OK bool `json:"ok"`
+	// Error describes why verification failed, if it did.
+	Error string `json:"error,omitempty"`
+}
+
+// componentReport is the result of verifying a single firmware component.
+type componentReport struct {
+	// Name is the name of the component, e.g. TrustedOS.
+	Name string `json:"name"`
+	// FirmwareSHA256 is the hex encoded SHA256 hash of the firmware extracted from the device.
+	FirmwareSHA256 string `json:"firmware_sha256"`
+	// Manifest is the manifest for the firmware, if it could be verified.
+	Manifest *ftlog.FirmwareRelease `json:"manifest,omitempty"`
+	// LogIndex is the FT log index of the manifest, according to the proof bundle.
+	LogIndex uint64 `json:"log_index"`
+	// CheckpointSize is the size of the checkpoint in the proof bundle.
+	CheckpointSize uint64 `json:"checkpoint_size"`
+	// BundleVerification is the result of checking that the proof bundle is self-consistent,
+	// and signed by the expected keys.
+	BundleVerification string `json:"bundle_verification"`
+	// ConsistencyProof is the result of checking that the proof bundle checkpoint is
+	// consistent with the current view of the FT log, or with the firmware pack.
+	ConsistencyProof string `json:"consistency_proof"`
+	// WitnessCosignatures is the result of checking that the proof bundle checkpoint is
+	// cosigned by enough witnesses to satisfy the witness policy.
+	WitnessCosignatures string `json:"witness_cosignatures"`
+	// HABSignature is the result of checking that the HAB signature on the MMC matches
+	// the one committed to by the manifest, for components which are HAB signed.
+	HABSignature string `json:"hab_signature,omitempty"`
+	// Updates describes any newer releases of the component in the FT log, if its
+	// manifest could be verified.
+	Updates *updateReport `json:"updates,omitempty"`
+	// Error describes why the component failed verification, if it did.
+	Error string `json:"error,omitempty"`
+}
+
+// writeReport writes the verification report to --report_file, or stdout if that isn't set.
+// verifyErr is the result of verification.
+func (v *verifier) writeReport(verifyErr error) error {
+	r := v.report
+	r.Time = time.Now().UTC()
+	r.OK = verifyErr == nil
+	if verifyErr != nil {
+		r.Error = verifyErr.Error()
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if *reportFile == "" {
+		_, err := os.Stdout.Write(b)
+		return err
+	}
+	if err := os.WriteFile(*reportFile, b, 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %v", err)
+	}
+	klog.Infof("Verification report written to %s", *reportFile)
+	return nil
+}
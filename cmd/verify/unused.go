@@ -0,0 +1,230 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/transparency-dev/armored-witness/internal/layout"
+	"k8s.io/klog/v2"
+)
+
+// extent is a range of bytes on the MMC.
+type extent struct {
+	// name describes what's stored in the extent.
+	name   string
+	offset int64
+	size   int64
+}
+
+func (e extent) end() int64 {
+	return e.offset + e.size
+}
+
+func (e extent) String() string {
+	return fmt.Sprintf("%s [0x%x, 0x%x)", e.name, e.offset, e.end())
+}
+
+// unusedExtents returns the parts of the bootloader, bootloader config, TrustedOS and
+// TrustedApplet regions of the MMC which aren't covered by any of the used extents.
+//
+// The applet data region isn't included, as its contents are managed by the applet.
+func unusedExtents(used []extent) []extent {
+	r := []extent{}
+	for _, reg := range []layout.Region{layout.Bootloader, layout.BootloaderConfig, layout.OS, layout.Applet} {
+		in := []extent{}
+		for _, e := range used {
+			if e.offset >= reg.Offset() && e.end() <= reg.Offset()+reg.Size() {
+				in = append(in, e)
+			}
+		}
+		slices.SortFunc(in, func(a, b extent) int { return cmp.Compare(a.offset, b.offset) })
+
+		// Gaps are named after whatever precedes them.
+		pos, name := reg.Offset(), "start of "+reg.Name
+		for _, e := range in {
+			if e.offset > pos {
+				r = append(r, extent{name: name, offset: pos, size: e.offset - pos})
+			}
+			if e.end() > pos {
+				pos, name = e.end(), "after "+e.name
+			}
+		}
+		if end := reg.Offset() + reg.Size(); end > pos {
+			r = append(r, extent{name: name, offset: pos, size: end - pos})
+		}
+	}
+	return r
+}
+
+// unusedData describes a run of blocks in an unused part of the MMC which hold data.
+type unusedData struct {
+	// Gap describes the unused part of the MMC the data was found in.
+	Gap string `json:"gap"`
+	// Offset is the MMC byte offset of the data, and Size its length in bytes.
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+	// SHA256 is the hex encoded SHA256 hash of the data.
+	SHA256 string `json:"sha256"`
+	// FollowsFirmware is true if the data starts right where the firmware or config
+	// before the gap ends. This is only a hint for whoever investigates it: the tail of an
+	// earlier, larger image would look like this, but so would a payload appended to the
+	// firmware.
+	FollowsFirmware bool `json:"follows_firmware"`
+}
+
+// markFollowsFirmware sets FollowsFirmware on the data found in the unused extents which
+// starts at the beginning of a gap following a used extent.
+func markFollowsFirmware(unused []extent, found []unusedData) {
+	for _, e := range unused {
+		// Gaps at the start of a region don't follow any firmware.
+		if !strings.HasPrefix(e.name, "after ") {
+			continue
+		}
+		for i := range found {
+			if found[i].Offset == e.offset {
+				found[i].FollowsFirmware = true
+			}
+		}
+	}
+}
+
+// scanUnused reads each of the unused extents of the MMC in f, and returns the runs of
+// blocks within them which aren't blank, i.e. which are neither all 0x00 nor all 0xff.
+func scanUnused(f *os.File, unused []extent) ([]unusedData, error) {
+	total := int64(0)
+	for _, e := range unused {
+		total += e.size
+	}
+	klog.Infof("Scanning 0x%x bytes of unused MMC for data...", total)
+	t := time.NewTicker(5 * time.Second)
+	defer t.Stop()
+
+	found := []unusedData{}
+	buf := make([]byte, dumpChunkSize)
+	done := int64(0)
+	for _, e := range unused {
+		var cur *unusedData
+		var h hash.Hash
+		flush := func() {
+			if cur != nil {
+				cur.SHA256 = hex.EncodeToString(h.Sum(nil))
+				found = append(found, *cur)
+				cur = nil
+			}
+		}
+		for off := e.offset; off < e.end(); {
+			select {
+			case <-t.C:
+				klog.Infof("   %3d%%", (done*100)/total)
+			default:
+			}
+			// Chunks are aligned to blocks, except where the extent itself isn't.
+			n := min(dumpChunkSize-off%layout.BlockSize, e.end()-off)
+			chunk := buf[:n]
+			if _, err := f.ReadAt(chunk, off); err != nil {
+				return nil, fmt.Errorf("failed to read MMC at offset 0x%x: %v", off, err)
+			}
+			for len(chunk) > 0 {
+				b := chunk[:min(int64(len(chunk)), layout.BlockSize-off%layout.BlockSize)]
+				if isBlank(b) {
+					flush()
+				} else {
+					if cur == nil {
+						cur, h = &unusedData{Gap: e.name, Offset: off}, sha256.New()
+					}
+					cur.Size += int64(len(b))
+					h.Write(b)
+				}
+				chunk = chunk[len(b):]
+				off += int64(len(b))
+				done += int64(len(b))
+			}
+		}
+		flush()
+	}
+	klog.Info("   100%")
+	return found, nil
+}
+
+// isBlank returns true if b is all zero or all erased bytes.
+func isBlank(b []byte) bool {
+	if len(b) == 0 {
+		return true
+	}
+	if b[0] != 0x00 && b[0] != 0xff {
+		return false
+	}
+	return bytes.Count(b, b[:1]) == len(b)
+}
+
+// checkUnused scans the parts of the MMC at dev which aren't used by any of the firmware
+// on it for unexpected data, and records what it finds.
+//
+// Data left behind in these gaps isn't covered by any of the other checks, so anything
+// found is listed for the operator to investigate. It isn't treated as a verification
+// failure, as it may just be left over from firmware which has since been replaced.
+func (v *verifier) checkUnused(dev string, fw *firmwares) error {
+	used := slices.Clone(fw.used)
+	if s := fw.Bootloader.HABSignature; len(s) > 0 {
+		used = append(used, extent{name: "bootloader HAB signature", offset: fw.bootloaderEnd, size: int64(len(s))})
+	}
+	unused := unusedExtents(used)
+	for _, e := range unused {
+		klog.V(1).Infof("Unused MMC extent %s", e)
+	}
+
+	f, err := os.OpenFile(dev, os.O_RDONLY, 0o400)
+	if err != nil {
+		return fmt.Errorf("error opening %v: %v", dev, err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			klog.Errorf("Error closing %v: %v", dev, err)
+		}
+	}()
+	found, err := scanUnused(f, unused)
+	if err != nil {
+		v.report.UnusedScan = resultFailed
+		return err
+	}
+	markFollowsFirmware(unused, found)
+	v.report.UnusedData = found
+	v.fwDetails["unused_data_ranges"] = fmt.Sprintf("%d", len(found))
+	if len(found) == 0 {
+		v.report.UnusedScan = resultOK
+		klog.Info("  ✅ Unused MMC areas are blank")
+		return nil
+	}
+	v.report.UnusedScan = resultFound
+	klog.Warningf("  ⚠️  Found data in %d unused MMC ranges, please report these:", len(found))
+	for _, d := range found {
+		note := ""
+		if d.FollowsFirmware {
+			note = " (directly follows firmware, may be left over from an earlier, larger image)"
+		}
+		klog.Warningf("     [0x%x, 0x%x) %s: 0x%x bytes, SHA256 %s%s", d.Offset, d.Offset+d.Size, d.Gap, d.Size, d.SHA256, note)
+	}
+	return nil
+}
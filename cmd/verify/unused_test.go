@@ -0,0 +1,41 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestMarkFollowsFirmware(t *testing.T) {
+	unused := []extent{
+		{name: "after TrustedOS", offset: 0x10000, size: 0x100000},
+		{name: "start of TrustedApplet", offset: 0x200000, size: 0x1000},
+	}
+	found := []unusedData{
+		// Data straight after the OS.
+		{Offset: 0x10000, Size: 0x2000},
+		// Data separated from the OS by blank blocks, however few.
+		{Offset: 0x12200, Size: 0x1000},
+		{Offset: 0x80000, Size: 0x200},
+		// Nothing precedes the start of a region.
+		{Offset: 0x200000, Size: 0x200},
+	}
+	markFollowsFirmware(unused, found)
+	for i, want := range []bool{true, false, false, false} {
+		if got := found[i].FollowsFirmware; got != want {
+			t.Errorf("found[%d] (offset 0x%x) FollowsFirmware = %t, want %t", i, found[i].Offset, got, want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/transparency-dev/armored-witness-common/release/firmware/ftlog"
+	"github.com/transparency-dev/armored-witness/internal/fetcher"
+	"k8s.io/klog/v2"
+)
+
+// Update statuses of installed firmware.
+const (
+	updateUpToDate = "up_to_date"
+	updateBehind   = "behind"
+)
+
+// updateReport describes how the installed firmware for a component compares with the
+// releases in the FT log.
+type updateReport struct {
+	// Status is one of up_to_date, behind, or unchecked_offline.
+	Status string `json:"status"`
+	// Newer lists the releases with higher versions than the installed firmware, in
+	// version order.
+	Newer []releaseReport `json:"newer,omitempty"`
+}
+
+// releaseReport identifies a firmware release found in the FT log.
+type releaseReport struct {
+	Version  string `json:"version"`
+	LogIndex uint64 `json:"log_index"`
+}
+
+func (r releaseReport) String() string {
+	return fmt.Sprintf("%s @ %d", r.Version, r.LogIndex)
+}
+
+// checkUpdates compares the installed firmware for each component whose manifest could be
+// verified with the releases in the FT log, and reports any newer releases.
+//
+// Only bootloader releases for the same HAB target as the installed one are considered.
+func (v *verifier) checkUpdates(ctx context.Context) error {
+	if v.packCP != nil {
+		klog.Warning("  ⚠️  Cannot check for newer releases offline")
+		for _, r := range v.report.Components {
+			r.Updates = &updateReport{Status: resultUncheckedOffline}
+		}
+		return nil
+	}
+	rs, err := v.scanLog(ctx)
+	if err != nil {
+		return err
+	}
+	for _, r := range v.report.Components {
+		if r.Manifest == nil {
+			continue
+		}
+		r.Updates = &updateReport{Status: updateUpToDate}
+		installed := releaseReport{Version: r.Manifest.Git.TagName.String(), LogIndex: r.LogIndex}
+		newer := newerReleases(*r.Manifest, rs)
+		if len(newer) == 0 {
+			klog.Infof("  ✅ %s: %s is up to date", r.Name, installed)
+			continue
+		}
+		r.Updates.Status = updateBehind
+		names := []string{}
+		for _, n := range newer {
+			r.Updates.Newer = append(r.Updates.Newer, releaseReport{Version: n.Release.Git.TagName.String(), LogIndex: n.Index})
+			names = append(names, n.String())
+		}
+		klog.Warningf("  ⚠️  %s: %s is %d releases behind, newer releases are %s", r.Name, installed, len(newer), strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// newerReleases returns the releases in rs of the same component as installed, but with
+// a higher version, ordered by version.
+//
+// Where there are several releases of the same version, only the last one in the log is
+// returned, as is the case when the update fetcher looks for the latest release.
+func newerReleases(installed ftlog.FirmwareRelease, rs []fetcher.Release) []fetcher.Release {
+	byVersion := map[string]fetcher.Release{}
+	for _, r := range rs {
+		if r.Release.Component != installed.Component || !installed.Git.TagName.LessThan(r.Release.Git.TagName) {
+			continue
+		}
+		if installed.HAB != nil && (r.Release.HAB == nil || r.Release.HAB.Target != installed.HAB.Target) {
+			continue
+		}
+		byVersion[r.Release.Git.TagName.String()] = r
+	}
+	newer := []fetcher.Release{}
+	for _, r := range byVersion {
+		newer = append(newer, r)
+	}
+	slices.SortFunc(newer, func(a, b fetcher.Release) int {
+		return a.Release.Git.TagName.Compare(b.Release.Git.TagName)
+	})
+	return newer
+}
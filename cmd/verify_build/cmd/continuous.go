@@ -29,6 +29,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/transparency-dev/armored-witness/cmd/verify_build/cmd/internal/build"
+	"github.com/transparency-dev/armored-witness/internal/policy"
 	"github.com/transparency-dev/merkle/proof"
 	"github.com/transparency-dev/merkle/rfc6962"
 	"github.com/transparency-dev/serverless-log/client"
@@ -51,6 +52,8 @@ func init() {
 	continuousCmd.Flags().Duration("poll_interval", 1*time.Minute, "The interval at which the log will be polled for new data.")
 	continuousCmd.Flags().String("state_file", "", "File path for where checkpoints should be stored")
 	continuousCmd.Flags().Uint64("start_index", 0, "Used for debugging to start verifying leaves from a given index. Only used if there is no prior checkpoint available.")
+	continuousCmd.Flags().String("witness_verifiers", "", "Comma separated list of verifier strings for witnesses, at least --witness_threshold of which must have cosigned each checkpoint from the log.")
+	continuousCmd.Flags().Int("witness_threshold", 0, "Number of --witness_verifiers which must have cosigned each checkpoint from the log.")
 }
 
 func continuous(cmd *cobra.Command, args []string) {
@@ -204,7 +207,20 @@ func stateTrackerFromFlags(ctx context.Context, f *pflag.FlagSet) (client.LogSta
 		return client.LogStateTracker{}, false, fmt.Errorf("unable to create new log signature verifier: %w", err)
 	}
 
-	lst, err := client.NewLogStateTracker(ctx, fetcher, rfc6962.DefaultHasher, state, lSigV, logOrigin, client.UnilateralConsensus(fetcher))
+	verifiers, err := f.GetString("witness_verifiers")
+	if err != nil {
+		return client.LogStateTracker{}, false, err
+	}
+	threshold, err := f.GetInt("witness_threshold")
+	if err != nil {
+		return client.LogStateTracker{}, false, err
+	}
+	wp, err := policy.FromFlags(verifiers, threshold)
+	if err != nil {
+		return client.LogStateTracker{}, false, fmt.Errorf("invalid witness policy: %w", err)
+	}
+
+	lst, err := client.NewLogStateTracker(ctx, fetcher, rfc6962.DefaultHasher, state, lSigV, logOrigin, wp.Consensus(fetcher))
 	return lst, state == nil, err
 }
 
@@ -21,6 +21,7 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/transparency-dev/armored-witness/internal/release"
 	"golang.org/x/exp/maps"
 	"k8s.io/klog/v2"
@@ -46,7 +47,10 @@ reproduced by building it again.`,
 }
 
 func init() {
-	rootCmd.PersistentFlags().String("template", "prod", fmt.Sprintf("One of %v", maps.Keys(release.Templates)))
+	rootCmd.PersistentFlags().String("template", "prod", fmt.Sprintf("One of %v, or a template defined in --template_file", maps.Keys(release.Templates)))
+	rootCmd.PersistentFlags().String("template_file", "", "Path to a JSON file, or a directory of JSON files, defining additional templates. The files must be pinned with --template_sha256 or signed by --template_verifier.")
+	rootCmd.PersistentFlags().String("template_sha256", "", "Comma separated list of hex encoded SHA256 hashes, one of which each --template_file must match.")
+	rootCmd.PersistentFlags().String("template_verifier", "", "Verifier string for the key which each --template_file must be a signed note from.")
 	rootCmd.PersistentFlags().String("log_url", "", "URL identifying the location of the log.")
 	rootCmd.PersistentFlags().String("log_origin", "", "The expected first line of checkpoints issued by the log.")
 	rootCmd.PersistentFlags().String("log_pubkey", "", "The log's public key.")
@@ -69,37 +73,51 @@ func Execute() {
 	}
 }
 
+// templateFlags maps the template keys this tool uses to its flags.
+var templateFlags = map[string]string{
+	"firmware_log_url":      "log_url",
+	"firmware_log_origin":   "log_origin",
+	"firmware_log_verifier": "log_pubkey",
+	"os_verifier_1":         "os_release_pubkey1",
+	"os_verifier_2":         "os_release_pubkey2",
+	"applet_verifier":       "applet_release_pubkey",
+	"boot_verifier":         "boot_release_pubkey",
+	"recovery_verifier":     "recovery_release_pubkey",
+}
+
 func applyFlagTemplate(cmd *cobra.Command, k string) {
-	klog.Infof("Using template flags %q", k)
-	tmpl, ok := release.Templates[k]
-	if !ok {
-		klog.Exitf("No such template %q", k)
+	flags := cmd.Flags()
+	src := release.TemplateSource{}
+	var err error
+	if src.Path, err = flags.GetString("template_file"); err != nil {
+		klog.Exitf("Failed to get `template_file` flag: %v", err)
 	}
-	// Define which flags we need, and map them to what they're called in the template.
-	flagMap := map[string]string{
-		"log_url":                 "firmware_log_url",
-		"log_origin":              "firmware_log_origin",
-		"log_pubkey":              "firmware_log_verifier",
-		"os_release_pubkey1":      "os_verifier_1",
-		"os_release_pubkey2":      "os_verifier_2",
-		"applet_release_pubkey":   "applet_verifier",
-		"boot_release_pubkey":     "boot_verifier",
-		"recovery_release_pubkey": "recovery_verifier",
+	if src.Verifier, err = flags.GetString("template_verifier"); err != nil {
+		klog.Exitf("Failed to get `template_verifier` flag: %v", err)
 	}
-	for f, m := range flagMap {
-		v, ok := tmpl[m]
-		if !ok {
-			klog.Exitf("Unknown template flag %q", m)
-		}
-		if c, err := cmd.Flags().GetString(f); err != nil {
-			klog.Exitf("Internal error applying template: %v", err)
-		} else if c != "" {
-			klog.Exitf("Cannot set --template and --%s", f)
-		}
-		klog.Infof("Using template flag setting --%v=%v", f, v)
-		if err := cmd.Flags().Set(f, v); err != nil {
-			klog.Exitf("Internal error setting templated flag %q: %v", f, err)
+	if h, err := flags.GetString("template_sha256"); err != nil {
+		klog.Exitf("Failed to get `template_sha256` flag: %v", err)
+	} else {
+		src.SHA256 = release.ParseHashes(h)
+	}
+	if err := src.Apply(k, cobraFlags{flags}, templateFlags); err != nil {
+		klog.Exitf("Failed to apply template %q: %v", k, err)
+	}
+}
 
-		}
+// cobraFlags allows templates to be applied to a command's flags.
+type cobraFlags struct {
+	fs *pflag.FlagSet
+}
+
+func (c cobraFlags) Lookup(name string) (string, bool) {
+	f := c.fs.Lookup(name)
+	if f == nil {
+		return "", false
 	}
+	return f.Value.String(), true
+}
+
+func (c cobraFlags) Set(name, value string) error {
+	return c.fs.Set(name, value)
 }
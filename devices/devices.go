@@ -41,6 +41,18 @@ type Device struct {
 	ID            string
 	BastionID     string
 	WitnessPubkey string
+	// AttestPubkey is the device's ID attestation public key, which signs its attestations.
+	AttestPubkey string
+}
+
+// ForSerial returns the device in m with the given serial number, if there is one.
+func ForSerial(m map[string]Device, serial string) (Device, bool) {
+	for _, d := range m {
+		if strings.HasSuffix(d.ID, "-"+serial) {
+			return d, true
+		}
+	}
+	return Device{}, false
 }
 
 type entry struct {
@@ -97,13 +109,59 @@ func parseFS(f embed.FS) (map[string]Device, error) {
 	return r, nil
 }
 
+// Layout returns the files, keyed by file name, which describe the device with the given
+// serial number in the format used in the ci and prod directories.
+//
+// attestPub is the device's ID attestation public key, and attestedID and attestedBastionID
+// are the signed notes attesting to its witness and bastion identities, as reported by the
+// device. attestedBastionID may be empty if the device doesn't have a bastion identity.
+func Layout(serial, attestPub, attestedID, attestedBastionID string) (map[string][]byte, error) {
+	if _, err := Attested(serial, attestPub, attestedID, attestedBastionID); err != nil {
+		return nil, err
+	}
+
+	r := map[string][]byte{
+		serial + ".pub":       []byte(attestPub),
+		serial + ".witness.0": []byte(attestedID),
+	}
+	if attestedBastionID != "" {
+		r[serial+".bastion.0"] = []byte(attestedBastionID)
+	}
+	return r, nil
+}
+
+// Attested returns the device with the given serial number described by the attestations
+// it reports, which are as for Layout.
+//
+// The attestations must be signed by attestPub, which must belong to the device.
+func Attested(serial, attestPub, attestedID, attestedBastionID string) (*Device, error) {
+	attestations := [][]byte{[]byte(attestedID)}
+	if attestedBastionID != "" {
+		attestations = append(attestations, []byte(attestedBastionID))
+	}
+	d, err := new(attestPub, attestations)
+	if err != nil {
+		return nil, err
+	}
+	if d.WitnessPubkey == "" {
+		return nil, fmt.Errorf("%s: no witness ID attestation found", d.ID)
+	}
+	if !strings.HasSuffix(d.ID, "-"+serial) {
+		return nil, fmt.Errorf("%s: attestation key does not belong to device %s", d.ID, serial)
+	}
+	if attestedBastionID != "" && d.BastionID == "" {
+		return nil, fmt.Errorf("%s: invalid bastion ID attestation", d.ID)
+	}
+	return d, nil
+}
+
 func new(attestPub string, attestations [][]byte) (*Device, error) {
 	v, err := note.NewVerifier(attestPub)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %v", attestPub, err)
 	}
 
-	d := &Device{ID: v.Name()}
+	d := &Device{ID: v.Name(), AttestPubkey: attestPub}
 
 	for _, a := range attestations {
 		n, err := note.Open(a, note.VerifierList(v))
@@ -20,6 +20,9 @@ func checkDevices(t *testing.T, d map[string]Device) {
 		if v.WitnessPubkey == "" {
 			t.Errorf("%s: no witness pubkey present", k)
 		}
+		if v.AttestPubkey == "" {
+			t.Errorf("%s: no attestation pubkey present", k)
+		}
 	}
 }
 
@@ -30,3 +33,102 @@ func TestCIFiles(t *testing.T) {
 func TestProdFiles(t *testing.T) {
 	checkDevices(t, Prod)
 }
+
+func TestLayout(t *testing.T) {
+	const serial = "720A9DEAD4390330"
+	read := func(n string) string {
+		t.Helper()
+		b, err := ci.ReadFile("ci/" + serial + "." + n)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		return string(b)
+	}
+	pub, witness, bastion := read("pub"), read("witness.0"), read("bastion.0")
+
+	for _, test := range []struct {
+		name      string
+		serial    string
+		pub       string
+		bastion   string
+		wantFiles int
+		wantErr   bool
+	}{
+		{
+			name:      "all identities",
+			serial:    serial,
+			pub:       pub,
+			bastion:   bastion,
+			wantFiles: 3,
+		}, {
+			name:      "no bastion",
+			serial:    serial,
+			pub:       pub,
+			wantFiles: 2,
+		}, {
+			name:    "wrong serial",
+			serial:  "720A9DEAD4390A2E",
+			pub:     pub,
+			bastion: bastion,
+			wantErr: true,
+		}, {
+			name:    "bad bastion attestation",
+			serial:  serial,
+			pub:     pub,
+			bastion: witness,
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			files, err := Layout(test.serial, test.pub, witness, test.bastion)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("Layout: %v, wantErr %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := len(files); got != test.wantFiles {
+				t.Fatalf("Got %d files, want %d", got, test.wantFiles)
+			}
+			for n, b := range files {
+				if want := read(n[len(serial)+1:]); string(b) != want {
+					t.Errorf("%s: got %q, want %q", n, b, want)
+				}
+			}
+		})
+	}
+}
+
+func TestForSerial(t *testing.T) {
+	const serial = "720A9DEAD4390330"
+	d, ok := ForSerial(CI, serial)
+	if !ok {
+		t.Fatalf("ForSerial(%s) found nothing", serial)
+	}
+	if want := "AW-ID-Attestation-" + serial; d.ID != want {
+		t.Errorf("Got ID %q, want %q", d.ID, want)
+	}
+	// A prefix of a serial number shouldn't match.
+	if d, ok := ForSerial(CI, serial[1:]); ok {
+		t.Errorf("ForSerial(%s) = %s, want nothing", serial[1:], d.ID)
+	}
+}
+
+func TestAttested(t *testing.T) {
+	const serial = "720A9DEAD4390330"
+	read := func(n string) string {
+		t.Helper()
+		b, err := ci.ReadFile("ci/" + serial + "." + n)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		return string(b)
+	}
+	d, err := Attested(serial, read("pub"), read("witness.0"), read("bastion.0"))
+	if err != nil {
+		t.Fatalf("Attested: %v", err)
+	}
+	if want := CI[d.ID]; *d != want {
+		t.Errorf("Got %+v, want %+v", *d, want)
+	}
+}
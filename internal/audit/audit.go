@@ -0,0 +1,238 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit provides a tamper-evident, append-only log of what happens to devices
+// as they are provisioned and verified.
+//
+// The log is a file containing one JSON encoded Entry per line. Each entry holds the
+// SHA256 hash of the line before it, so that modifying, removing, or reordering entries
+// is detected by Verify. Removing entries from the end of the log can only be detected
+// by comparing the hash of the last entry, as returned by Verify, with one recorded
+// elsewhere.
+//
+// Several processes may append to the same log, e.g. provision bench workers and verify
+// sharing the default log. Each append holds an exclusive lock on the file, and first
+// catches up with any entries added by other processes since the last one.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Events recorded in the log.
+const (
+	EventDetected    = "device_detected"
+	EventFlashed     = "image_flashed"
+	EventWiped       = "data_wiped"
+	EventFused       = "fused"
+	EventStatus      = "status"
+	EventProvisioned = "provisioned"
+	EventUpgraded    = "upgraded"
+	EventVerified    = "verified"
+	EventFailed      = "failed"
+)
+
+// Entry is a single event in the log.
+type Entry struct {
+	// Seq is the position of the entry in the log, starting from 1.
+	Seq uint64 `json:"seq"`
+	// Time is the time at which the entry was added.
+	Time time.Time `json:"time"`
+	// Tool is the name of the tool which added the entry.
+	Tool string `json:"tool"`
+	// Device is the serial number of the device concerned, if known.
+	Device string `json:"device,omitempty"`
+	// Event is one of the Event constants above.
+	Event string `json:"event"`
+	// Details holds event specific information, e.g. the digest of a flashed image.
+	Details map[string]string `json:"details,omitempty"`
+	// Prev is the hex encoded SHA256 hash of the previous line in the log, or empty for
+	// the first entry.
+	Prev string `json:"prev"`
+}
+
+// Log is an audit log file which entries can be appended to.
+type Log struct {
+	tool string
+
+	mu sync.Mutex
+	f  *os.File
+	// seq and prev are the sequence number and hash of the last entry in the log as of
+	// size bytes, the length of the file when it was last read or written.
+	seq  uint64
+	prev string
+	size int64
+}
+
+// Open opens the audit log at path for appending entries from the named tool, creating it
+// if necessary. The existing contents of the log are verified before anything is appended.
+func Open(path, tool string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	l := &Log{tool: tool, f: f}
+	if err := lock(f); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to lock audit log: %v", err)
+	}
+	defer func() { _ = unlock(f) }()
+	if err := l.catchUp(); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("existing audit log %s failed verification: %v", path, err)
+	}
+	return l, nil
+}
+
+// catchUp verifies the entries added to the log file since it was last read or written,
+// and moves the head of the log past them. The file must be locked.
+func (l *Log) catchUp() error {
+	fi, err := l.f.Stat()
+	if err != nil {
+		return err
+	}
+	size := fi.Size()
+	switch {
+	case size < l.size:
+		return fmt.Errorf("log has been truncated from %d to %d bytes", l.size, size)
+	case size == l.size:
+		return nil
+	}
+	es, head, err := verifyFrom(io.NewSectionReader(l.f, l.size, size-l.size), l.seq, l.prev)
+	if err != nil {
+		return err
+	}
+	l.seq += uint64(len(es))
+	l.prev = head
+	l.size = size
+	return nil
+}
+
+// Append adds an entry for the event to the log, and syncs it to disk.
+func (l *Log) Append(device, event string, details map[string]string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := lock(l.f); err != nil {
+		return fmt.Errorf("failed to lock audit log: %v", err)
+	}
+	defer func() { _ = unlock(l.f) }()
+	// Other processes may have appended to the log since we last did.
+	if err := l.catchUp(); err != nil {
+		return fmt.Errorf("audit log failed verification: %v", err)
+	}
+
+	e := Entry{
+		Seq:     l.seq + 1,
+		Time:    time.Now().UTC(),
+		Tool:    l.tool,
+		Device:  device,
+		Event:   event,
+		Details: details,
+		Prev:    l.prev,
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit log entry: %v", err)
+	}
+	if _, err := l.f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log entry: %v", err)
+	}
+	if err := l.f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log: %v", err)
+	}
+	l.seq = e.Seq
+	l.prev = hash(b)
+	l.size += int64(len(b) + 1)
+	return nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+// Verify checks the hash chain of the log read from r, and returns its entries along with
+// the hex encoded SHA256 hash of the last entry, which commits to every entry in the log.
+//
+// An empty log has an empty head.
+func Verify(r io.Reader) ([]Entry, string, error) {
+	return verify(r)
+}
+
+func verify(r io.Reader) ([]Entry, string, error) {
+	return verifyFrom(r, 0, "")
+}
+
+// verifyFrom checks the hash chain of entries read from r, which follow the entry with
+// sequence number seq and hash prev, and returns them along with the hash of the last one.
+func verifyFrom(r io.Reader, seq uint64, prev string) ([]Entry, string, error) {
+	es := []Entry{}
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		switch {
+		case errors.Is(err, io.EOF) && len(line) == 0:
+			return es, prev, nil
+		case errors.Is(err, io.EOF):
+			return nil, "", fmt.Errorf("entry %d is incomplete", seq+uint64(len(es))+1)
+		case err != nil:
+			return nil, "", err
+		}
+		line = bytes.TrimSuffix(line, []byte("\n"))
+
+		e := Entry{}
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, "", fmt.Errorf("entry %d is invalid: %v", seq+uint64(len(es))+1, err)
+		}
+		if want := seq + uint64(len(es)) + 1; e.Seq != want {
+			return nil, "", fmt.Errorf("entry %d has sequence number %d", want, e.Seq)
+		}
+		if e.Prev != prev {
+			return nil, "", fmt.Errorf("entry %d does not follow entry %d: hash chain is broken", e.Seq, e.Seq-1)
+		}
+		prev = hash(line)
+		es = append(es, e)
+	}
+}
+
+func hash(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// DefaultPath returns the location of the audit log shared by the provision and verify
+// tools if no other location is given.
+func DefaultPath() (string, error) {
+	d, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, "armored-witness", "audit.jsonl"), nil
+}
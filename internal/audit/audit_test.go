@@ -0,0 +1,127 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	l, err := Open(path, "provision")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := l.Append("A1B2", EventDetected, nil); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := l.Append("A1B2", EventFlashed, map[string]string{"image": "os", "sha256": "00"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Reopening the log must continue the existing chain.
+	l, err = Open(path, "verify")
+	if err != nil {
+		t.Fatalf("Open existing: %v", err)
+	}
+	if err := l.Append("A1B2", EventVerified, nil); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	es, _, err := Verify(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got, want := len(es), 3; got != want {
+		t.Fatalf("Got %d entries, want %d", got, want)
+	}
+	if es[2].Tool != "verify" || es[1].Details["image"] != "os" {
+		t.Errorf("Got unexpected entries %+v", es)
+	}
+
+	lines := bytes.SplitAfter(b, []byte("\n"))
+	for _, test := range []struct {
+		desc string
+		log  []byte
+	}{
+		{desc: "modified", log: bytes.Replace(b, []byte(`"image":"os"`), []byte(`"image":"applet"`), 1)},
+		{desc: "removed", log: bytes.Join([][]byte{lines[0], lines[2]}, nil)},
+		{desc: "reordered", log: bytes.Join([][]byte{lines[1], lines[0], lines[2]}, nil)},
+		{desc: "incomplete", log: b[:len(b)-1]},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if _, _, err := Verify(bytes.NewReader(test.log)); err == nil {
+				t.Error("Verify succeeded, want error")
+			}
+		})
+	}
+
+	// A tampered log must not be appended to.
+	if err := os.WriteFile(path, lines[1], 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := Open(path, "provision"); err == nil {
+		t.Error("Open of tampered log succeeded, want error")
+	}
+}
+
+func TestLogConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	// As when provision bench workers and verify share the default log.
+	a, err := Open(path, "provision")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	b, err := Open(path, "verify")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for i, l := range []*Log{a, b, a, b, b, a} {
+		if err := l.Append("A1B2", EventStatus, nil); err != nil {
+			t.Fatalf("Append %d: %v", i, err)
+		}
+	}
+	for _, l := range []*Log{a, b} {
+		if err := l.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+	es, _, err := Verify(f)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got, want := len(es), 6; got != want {
+		t.Errorf("Got %d entries, want %d", got, want)
+	}
+}
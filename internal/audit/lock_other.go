@@ -0,0 +1,34 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !unix
+
+package audit
+
+import (
+	"errors"
+	"os"
+)
+
+// lock takes an exclusive lock on f.
+//
+// This is currently only supported on Unix, and appending to the log fails elsewhere rather
+// than risk breaking its hash chain.
+func lock(f *os.File) error {
+	return errors.New("audit log locking is not supported on this platform")
+}
+
+func unlock(f *os.File) error {
+	return nil
+}
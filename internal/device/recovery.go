@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -36,7 +37,15 @@ import (
 //
 // Returns the HID device and detected block device path, or an error.
 func BootIntoRecovery(ctx context.Context, recoveryFirmware []byte, blockDeviceGlob string) (*Target, string, error) {
-	target, err := waitForHIDDevice(ctx)
+	return BootIntoRecoveryOnPort(ctx, "", recoveryFirmware, blockDeviceGlob)
+}
+
+// BootIntoRecoveryOnPort is like BootIntoRecovery, but only considers devices connected via
+// the given USB port (as returned by USBPort).
+//
+// If port is empty, devices on any port are considered.
+func BootIntoRecoveryOnPort(ctx context.Context, port string, recoveryFirmware []byte, blockDeviceGlob string) (*Target, string, error) {
+	target, err := waitForHIDDevice(ctx, port)
 	if err != nil {
 		return nil, "", err
 	}
@@ -44,7 +53,7 @@ func BootIntoRecovery(ctx context.Context, recoveryFirmware []byte, blockDeviceG
 	// SDP boot recovery image on device.
 	// Booting the recovery image causes the device re-appear as a USB Mass Storage device.
 	// So we'll wait for that to happen, and figure out which /dev/ entry corresponds to it.
-	bDev, err := waitForBlockDevice(ctx, blockDeviceGlob, func() error {
+	bDev, err := waitForBlockDevice(ctx, blockDeviceGlob, port, func() error {
 		if err := target.BootIMX(recoveryFirmware); err != nil {
 			return fmt.Errorf("failed to SDP boot recovery image on %v: %v", target.DeviceInfo.Path, err)
 		}
@@ -60,7 +69,9 @@ func BootIntoRecovery(ctx context.Context, recoveryFirmware []byte, blockDeviceG
 
 // waitForHIDDevice waits for an unprovisioned armored witness device
 // to appear on the USB bus.
-func waitForHIDDevice(ctx context.Context) (*Target, error) {
+//
+// If port is not empty, only devices connected via that USB port are considered.
+func waitForHIDDevice(ctx context.Context, port string) (*Target, error) {
 	klog.Info("Waiting for device to be detected...")
 	for {
 		select {
@@ -72,16 +83,19 @@ func waitForHIDDevice(ctx context.Context) (*Target, error) {
 				klog.Warningf("Failed to detect devices: %v", err)
 				continue
 			}
-			if len(targets) == 0 {
-				continue
+			for _, t := range targets {
+				if onPort(t.DeviceInfo.Path, port) {
+					return t, nil
+				}
 			}
-			return targets[0], nil
 		}
 	}
 }
 
 // waitForBlockDevice runs f, and waits for a block device matching glob to appear.
-func waitForBlockDevice(ctx context.Context, glob string, f func() error) (string, error) {
+//
+// If port is not empty, only block devices connected via that USB port are considered.
+func waitForBlockDevice(ctx context.Context, glob string, port string, f func() error) (string, error) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return "", fmt.Errorf("failed to create fs watcher: %v", err)
@@ -113,7 +127,7 @@ func waitForBlockDevice(ctx context.Context, glob string, f func() error) (strin
 			if err != nil {
 				klog.Exitf("error testing filename %q against glob %q: %v", e.Name, glob, err)
 			}
-			if matched && e.Has(fsnotify.Create) {
+			if matched && e.Has(fsnotify.Create) && onPort(e.Name, port) {
 				// At least on linux, it takes a while for the device to become usable
 				klog.Info("Waiting for block device to settle...")
 				if err := probeDevice(ctx, e.Name); err != nil {
@@ -155,3 +169,29 @@ func probeDevice(ctx context.Context, p string) error {
 		return nil
 	}
 }
+
+// onPort returns true if port is empty, or the device at path is connected via that USB port.
+func onPort(path string, port string) bool {
+	if port == "" {
+		return true
+	}
+	p, err := USBPort(path)
+	if err != nil {
+		klog.V(1).Infof("Unable to determine USB port for %s: %v", path, err)
+		return false
+	}
+	return p == port
+}
+
+// serialFromBlockDevPattern matches the device serial number in the /dev/disk/by-id/
+// names given to the block device presented by the recovery image.
+var serialFromBlockDevPattern = regexp.MustCompile(`_([0-9A-F]{16})-0:0$`)
+
+// SerialFromBlockDevice returns the device serial number embedded in the name of the block
+// device presented by the recovery image, or an empty string if there isn't one.
+func SerialFromBlockDevice(path string) string {
+	if m := serialFromBlockDevPattern.FindStringSubmatch(path); m != nil {
+		return m[1]
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package device
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// usbPortPattern matches sysfs path elements which identify a USB device by its
+// bus and port chain, e.g. "1-2" or "3-1.4.2".
+var usbPortPattern = regexp.MustCompile(`^\d+-\d+(\.\d+)*$`)
+
+// USBPort returns an identifier for the physical USB port via which the device at
+// the given /dev path (e.g. a hidraw or block device, or a symlink to one) is
+// connected to the host.
+//
+// The identifier remains the same when a device is rebooted into a different mode,
+// so it can be used to pair up the different faces a single device presents to the
+// host, e.g. in SDP mode, as a recovery mode block device, and as a witness.
+func USBPort(path string) (string, error) {
+	dev, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	base := filepath.Base(dev)
+	class := "block"
+	if strings.HasPrefix(base, "hidraw") {
+		class = "hidraw"
+	}
+	sys, err := filepath.EvalSymlinks(filepath.Join("/sys/class", class, base))
+	if err != nil {
+		return "", err
+	}
+	// The sysfs path looks something like:
+	//   /sys/devices/pci0000:00/0000:00:14.0/usb1/1-2/1-2.3/1-2.3:1.0/host3/target3:0:0/3:0:0:0/block/sdc
+	// so the last element which looks like a port chain identifies the USB device.
+	elems := strings.Split(sys, "/")
+	for i := len(elems) - 1; i >= 0; i-- {
+		if usbPortPattern.MatchString(elems[i]) {
+			return elems[i], nil
+		}
+	}
+	return "", fmt.Errorf("%s (%s) does not appear to be a USB device", path, sys)
+}
@@ -0,0 +1,29 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+
+package device
+
+import (
+	"errors"
+)
+
+// USBPort returns an identifier for the physical USB port via which the device at
+// the given /dev path is connected to the host.
+//
+// This is currently only supported on Linux.
+func USBPort(path string) (string, error) {
+	return "", errors.New("USB topology detection is not supported on this platform")
+}
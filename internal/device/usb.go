@@ -18,11 +18,14 @@
 package device
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	flynn_hid "github.com/flynn/hid"
 	"github.com/flynn/u2f/u2fhid"
 	"google.golang.org/protobuf/proto"
+	"k8s.io/klog/v2"
 
 	"github.com/transparency-dev/armored-witness-os/api"
 )
@@ -30,6 +33,14 @@ import (
 // DetectU2F returns the first U2F device found which matches
 // the armored witness vendor and product IDs.
 func DetectU2F() (string, *u2fhid.Device, error) {
+	return DetectU2FOnPort("")
+}
+
+// DetectU2FOnPort is like DetectU2F, but only considers devices connected via the
+// given USB port (as returned by USBPort).
+//
+// If port is empty, devices on any port are considered.
+func DetectU2FOnPort(port string) (string, *u2fhid.Device, error) {
 	devices, err := flynn_hid.Devices()
 	if err != nil {
 		return "", nil, err
@@ -38,7 +49,8 @@ func DetectU2F() (string, *u2fhid.Device, error) {
 	for _, d := range devices {
 		if d.UsagePage == api.HIDUsagePage &&
 			d.VendorID == api.VendorID &&
-			d.ProductID == api.ProductID {
+			d.ProductID == api.ProductID &&
+			onPort(d.Path, port) {
 
 			dev, err := u2fhid.Open(d)
 			return d.Path, dev, err
@@ -48,6 +60,31 @@ func DetectU2F() (string, *u2fhid.Device, error) {
 	return "", nil, nil
 }
 
+// WaitForU2F waits for a device running armored witness firmware
+// to appear on the USB bus.
+// Returns the device path & opened device.
+//
+// If port is not empty, only devices connected via that USB port are considered.
+func WaitForU2F(ctx context.Context, port string) (string, *u2fhid.Device, error) {
+	klog.Info("Waiting for armored witness device to be detected...")
+	for {
+		select {
+		case <-ctx.Done():
+			return "", nil, ctx.Err()
+		case <-time.After(time.Second):
+			p, target, err := DetectU2FOnPort(port)
+			if err != nil {
+				klog.Warningf("Failed to detect devices: %v", err)
+				continue
+			}
+			if target == nil {
+				continue
+			}
+			return p, target, nil
+		}
+	}
+}
+
 // WitnessStatus issues the Status command to the armored witness via HID and returns the result.
 func WitnessStatus(dev *u2fhid.Device) (*api.Status, error) {
 	res, err := dev.Command(api.U2FHID_ARMORY_INF, nil)
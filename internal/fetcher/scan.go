@@ -0,0 +1,92 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetcher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/transparency-dev/armored-witness-common/release/firmware/ftlog"
+	"github.com/transparency-dev/armored-witness-common/release/firmware/update"
+	"github.com/transparency-dev/formats/log"
+	"golang.org/x/mod/sumdb/note"
+	"k8s.io/klog/v2"
+)
+
+// Release is a firmware release found in the FT log.
+type Release struct {
+	Index   uint64
+	Release ftlog.FirmwareRelease
+	// Manifest is the signed manifest, exactly as it appears in the log.
+	Manifest []byte
+}
+
+func (r Release) String() string {
+	return fmt.Sprintf("%s @ %d", r.Release.Git.TagName, r.Index)
+}
+
+// Scan returns every firmware release in the log, in log order, along with the raw
+// checkpoint which they were fetched against.
+//
+// update.Fetcher only keeps the latest release of each component when it scans the log,
+// so this is used where all the releases are needed. Callers needing the latest releases
+// as well should use Latest on the result, and not scan the log a second time.
+func Scan(ctx context.Context, s update.FetchSession, origin string, v note.Verifier) ([]Release, []byte, error) {
+	// The session doesn't expose its checkpoint directly, but all bundles carry it.
+	b, _, err := s.Fetch(ctx, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch log checkpoint: %v", err)
+	}
+	cp, _, _, err := log.ParseCheckpoint(b.Checkpoint, origin, v)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse log checkpoint: %v", err)
+	}
+
+	klog.Infof("Scanning %d entries in the FT log for releases...", cp.Size)
+	rs := []Release{}
+	for i := uint64(0); i < cp.Size; i++ {
+		b, r, err := s.Fetch(ctx, i)
+		if err != nil {
+			// This is also what update.Fetcher does when scanning for the latest release.
+			klog.Errorf("Failed to verifiably fetch leaf at index %d: %v", i, err)
+			continue
+		}
+		rs = append(rs, Release{Index: i, Release: *r, Manifest: b.Manifest})
+	}
+	return rs, b.Checkpoint, nil
+}
+
+// Latest returns the highest release of component in rs, or nil if there isn't one.
+//
+// Boot and recovery releases for HAB targets other than habTarget are ignored, unless
+// habTarget is empty. As with update.Fetcher, where there are several releases with the
+// same version, the one which was logged last is chosen.
+func Latest(rs []Release, component string, habTarget string) *Release {
+	var latest *Release
+	for i := range rs {
+		r := &rs[i]
+		if r.Release.Component != component {
+			continue
+		}
+		isHAB := component == ftlog.ComponentBoot || component == ftlog.ComponentRecovery
+		if isHAB && habTarget != "" && (r.Release.HAB == nil || r.Release.HAB.Target != habTarget) {
+			continue
+		}
+		if latest == nil || !r.Release.Git.TagName.LessThan(latest.Release.Git.TagName) {
+			latest = r
+		}
+	}
+	return latest
+}
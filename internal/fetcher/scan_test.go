@@ -0,0 +1,67 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetcher
+
+import (
+	"testing"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/transparency-dev/armored-witness-common/release/firmware/ftlog"
+)
+
+func testRelease(i uint64, component, version, habTarget string) Release {
+	r := Release{Index: i}
+	r.Release.Component = component
+	r.Release.Git.TagName = *semver.New(version)
+	if habTarget != "" {
+		r.Release.HAB = &ftlog.HAB{Target: habTarget}
+	}
+	return r
+}
+
+func TestLatest(t *testing.T) {
+	rs := []Release{
+		testRelease(0, ftlog.ComponentOS, "1.0.0", ""),
+		testRelease(1, ftlog.ComponentOS, "1.2.0", ""),
+		testRelease(2, ftlog.ComponentApplet, "2.0.0", ""),
+		testRelease(3, ftlog.ComponentOS, "1.1.0", ""),
+		testRelease(4, ftlog.ComponentOS, "1.2.0", ""),
+		testRelease(5, ftlog.ComponentBoot, "1.0.0", "ci"),
+		testRelease(6, ftlog.ComponentBoot, "1.1.0", "prod"),
+	}
+	for _, test := range []struct {
+		desc      string
+		component string
+		habTarget string
+		want      int64
+	}{
+		{desc: "last logged of highest version", component: ftlog.ComponentOS, want: 4},
+		{desc: "other component", component: ftlog.ComponentApplet, want: 2},
+		{desc: "HAB target", component: ftlog.ComponentBoot, habTarget: "ci", want: 5},
+		{desc: "any HAB target", component: ftlog.ComponentBoot, want: 6},
+		{desc: "no matching HAB target", component: ftlog.ComponentBoot, habTarget: "dev", want: -1},
+		{desc: "no releases", component: ftlog.ComponentRecovery, want: -1},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got := Latest(rs, test.component, test.habTarget)
+			switch {
+			case got == nil && test.want >= 0:
+				t.Fatalf("Latest() = nil, want index %d", test.want)
+			case got != nil && int64(got.Index) != test.want:
+				t.Fatalf("Latest() = %s, want index %d", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fuseauth provides support for the signed authorizations which are required
+// before devices may be HAB fused.
+//
+// An authorization is a note, signed by an approver other than the operator doing the
+// fusing, whose text is formed of:
+//
+//  1. A line with the text "ArmoredWitness fuse authorization v1".
+//  2. A line of the form "hab_target <release environment>".
+//  3. A line of the form "expires <RFC 3339 time>".
+//  4. One line for each device which may be fused, of the form "serial <serial number>".
+package fuseauth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/sumdb/note"
+)
+
+// Header is the first line of the text of a fuse authorization note.
+const Header = "ArmoredWitness fuse authorization v1"
+
+// Authorization describes the devices which may be fused, and what they may be fused to.
+type Authorization struct {
+	// HABTarget is the release environment the devices may be fused to.
+	HABTarget string
+	// Expires is the time after which the authorization may no longer be used.
+	Expires time.Time
+	// Serials are the serial numbers of the devices which may be fused.
+	Serials []string
+}
+
+// Sign returns the authorization as a note signed by s.
+func Sign(a *Authorization, s note.Signer) ([]byte, error) {
+	if err := a.check(); err != nil {
+		return nil, err
+	}
+	b := &strings.Builder{}
+	fmt.Fprintln(b, Header)
+	fmt.Fprintf(b, "hab_target %s\n", a.HABTarget)
+	fmt.Fprintf(b, "expires %s\n", a.Expires.UTC().Format(time.RFC3339))
+	for _, serial := range a.Serials {
+		fmt.Fprintf(b, "serial %s\n", serial)
+	}
+	return note.Sign(&note.Note{Text: b.String()}, s)
+}
+
+// Open verifies the signature on an authorization note using v, and returns the
+// authorization it contains.
+func Open(b []byte, v note.Verifiers) (*Authorization, error) {
+	n, err := note.Open(b, v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open authorization: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(n.Text, "\n"), "\n")
+	if lines[0] != Header {
+		return nil, errors.New("invalid authorization header")
+	}
+	if len(lines) < 3 {
+		return nil, errors.New("authorization is truncated")
+	}
+	a := &Authorization{}
+	if t, ok := strings.CutPrefix(lines[1], "hab_target "); ok {
+		a.HABTarget = t
+	} else {
+		return nil, fmt.Errorf("invalid authorization line %q, want hab_target", lines[1])
+	}
+	if e, ok := strings.CutPrefix(lines[2], "expires "); ok {
+		if a.Expires, err = time.Parse(time.RFC3339, e); err != nil {
+			return nil, fmt.Errorf("invalid authorization expiry: %v", err)
+		}
+	} else {
+		return nil, fmt.Errorf("invalid authorization line %q, want expires", lines[2])
+	}
+	for _, l := range lines[3:] {
+		serial, ok := strings.CutPrefix(l, "serial ")
+		if !ok {
+			return nil, fmt.Errorf("invalid authorization line %q, want serial", l)
+		}
+		a.Serials = append(a.Serials, serial)
+	}
+	if err := a.check(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// check returns an error if the authorization is incomplete, or can't be represented
+// in a note.
+func (a *Authorization) check() error {
+	if !validField(a.HABTarget) {
+		return fmt.Errorf("invalid HAB target %q", a.HABTarget)
+	}
+	if a.Expires.IsZero() {
+		return errors.New("authorization has no expiry")
+	}
+	if len(a.Serials) == 0 {
+		return errors.New("authorization has no serial numbers")
+	}
+	for _, serial := range a.Serials {
+		if !validField(serial) {
+			return fmt.Errorf("invalid serial number %q", serial)
+		}
+	}
+	return nil
+}
+
+func validField(f string) bool {
+	return f != "" && !strings.ContainsAny(f, " \n")
+}
+
+// Permits returns an error unless the authorization allows the device with the given
+// serial number to be fused to the habTarget release environment at time now.
+func (a *Authorization) Permits(serial, habTarget string, now time.Time) error {
+	if a.HABTarget != habTarget {
+		return fmt.Errorf("authorization is for release environment %q, not %q", a.HABTarget, habTarget)
+	}
+	if now.After(a.Expires) {
+		return fmt.Errorf("authorization expired at %s", a.Expires.UTC().Format(time.RFC3339))
+	}
+	if !slices.Contains(a.Serials, serial) {
+		return fmt.Errorf("device %s is not authorized to be fused", serial)
+	}
+	return nil
+}
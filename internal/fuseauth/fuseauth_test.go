@@ -0,0 +1,94 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuseauth
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"golang.org/x/mod/sumdb/note"
+)
+
+func TestSignOpen(t *testing.T) {
+	skey, vkey, err := note.GenerateKey(rand.Reader, "approver")
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	s, err := note.NewSigner(skey)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	v, err := note.NewVerifier(vkey)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+
+	expires := time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC)
+	b, err := Sign(&Authorization{HABTarget: "ci", Expires: expires, Serials: []string{"A1B2", "C3D4"}}, s)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	a, err := Open(b, note.VerifierList(v))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	before := expires.Add(-time.Hour)
+	for _, test := range []struct {
+		serial, habTarget string
+		now               time.Time
+		wantErr           bool
+	}{
+		{serial: "C3D4", habTarget: "ci", now: before},
+		{serial: "E5F6", habTarget: "ci", now: before, wantErr: true},
+		{serial: "A1B2", habTarget: "prod", now: before, wantErr: true},
+		{serial: "A1B2", habTarget: "ci", now: expires.Add(time.Second), wantErr: true},
+	} {
+		if err := a.Permits(test.serial, test.habTarget, test.now); (err != nil) != test.wantErr {
+			t.Errorf("Permits(%q, %q, %v): got err %v, want err %t", test.serial, test.habTarget, test.now, err, test.wantErr)
+		}
+	}
+
+	// Authorizations must be signed by the expected key.
+	_, other, err := note.GenerateKey(rand.Reader, "approver")
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	ov, err := note.NewVerifier(other)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	if _, err := Open(b, note.VerifierList(ov)); err == nil {
+		t.Error("Open with wrong verifier succeeded, want error")
+	}
+
+	// Anything other than an authorization must be rejected, even if correctly signed.
+	for _, text := range []string{
+		"ArmoredWitness provisioning record v1\n{}\n",
+		Header + "\n",
+		Header + "\nhab_target ci\nexpires 2026-10-20T12:00:00Z\n",
+		Header + "\nhab_target ci\nexpires tomorrow\nserial A1B2\n",
+		Header + "\nexpires 2026-10-20T12:00:00Z\nserial A1B2\n",
+		Header + "\nhab_target ci\nexpires 2026-10-20T12:00:00Z\nserials A1B2\n",
+	} {
+		n, err := note.Sign(&note.Note{Text: text}, s)
+		if err != nil {
+			t.Fatalf("note.Sign: %v", err)
+		}
+		if _, err := Open(n, note.VerifierList(v)); err == nil {
+			t.Errorf("Open(%q) succeeded, want error", text)
+		}
+	}
+}
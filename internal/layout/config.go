@@ -0,0 +1,47 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package layout
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io"
+
+	"github.com/transparency-dev/armored-witness-boot/config"
+)
+
+// ReadConfig reads and decodes the firmware config GOB stored at the given block of the
+// MMC.
+//
+// This structure holds the location on MMC at which the corresponding firmware
+// image can be found, as well as the FT proofbundle for that image.
+//
+// The length in bytes of the encoded config is also returned.
+func ReadConfig(r io.ReaderAt, block int64) (*config.Config, int64, error) {
+	buf := make([]byte, config.MaxLength)
+	if _, err := r.ReadAt(buf, block*BlockSize); err != nil {
+		return nil, 0, fmt.Errorf("failed to read config region @ block %d: %v", block, err)
+	}
+
+	cfg := &config.Config{}
+	// A bytes.Reader is an io.ByteReader, so the decoder reads no further than the config.
+	br := bytes.NewReader(buf)
+	if err := gob.NewDecoder(br).Decode(cfg); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode config: %v", err)
+	}
+
+	return cfg, int64(len(buf) - br.Len()), nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package layout
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/transparency-dev/armored-witness-boot/config"
+)
+
+func TestReadConfig(t *testing.T) {
+	want := &config.Config{Offset: 0x5000 * BlockSize, Size: 1234, Bundle: config.ProofBundle{Manifest: []byte("manifest"), LogIndex: 42}}
+	b, err := want.Encode()
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	// The config is followed by firmware, which mustn't be counted in its length.
+	mmc := make([]byte, 2*BlockSize+config.MaxLength)
+	copy(mmc[2*BlockSize:], b)
+	copy(mmc[2*BlockSize+len(b):], []byte("\x7fELF"))
+
+	got, n, err := ReadConfig(bytes.NewReader(mmc), 2)
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	if n != int64(len(b)) {
+		t.Errorf("ReadConfig returned length %d, want %d", n, len(b))
+	}
+	if got.Size != want.Size || got.Bundle.LogIndex != want.Bundle.LogIndex || !bytes.Equal(got.Bundle.Manifest, want.Bundle.Manifest) {
+		t.Errorf("ReadConfig = %+v, want %+v", got, want)
+	}
+
+	if _, _, err := ReadConfig(bytes.NewReader(mmc), 1); err == nil {
+		t.Error("ReadConfig of a block without a config succeeded, want error")
+	}
+}
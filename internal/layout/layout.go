@@ -0,0 +1,105 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package layout describes how the MMC storage on the armored witness is divided up.
+package layout
+
+import (
+	"fmt"
+)
+
+// BlockSize is the size in bytes of a block on the armored witness MMC.
+const BlockSize = 512
+
+// Region is a contiguous range of blocks on the MMC which is used for a single purpose.
+type Region struct {
+	// Name is a human readable name for the region.
+	Name string
+	// Block is the first block of the region.
+	Block int64
+	// NumBlocks is the number of blocks in the region.
+	NumBlocks int64
+}
+
+var (
+	// Bootloader holds the bootloader IMX image, which must start at this location as
+	// it's loaded from here by the i.MX ROM bootloader.
+	Bootloader = Region{Name: "bootloader", Block: 0x2, NumBlocks: 0x4FB0 - 0x2}
+	// BootloaderConfig holds the GOB encoded config for the bootloader.
+	// In contrast to the other firmware below, where each firmware is preceded by its
+	// config GOB, the bootloader config is stored separately due to the hard requirement
+	// for the bootloader location imposed by the i.MX ROM bootloader.
+	BootloaderConfig = Region{Name: "bootloader config", Block: 0x4FB0, NumBlocks: 0x5000 - 0x4FB0}
+	// OS holds the config GOB for the TrustedOS, followed by the TrustedOS ELF.
+	OS = Region{Name: "TrustedOS", Block: 0x5000, NumBlocks: 0x200000 - 0x5000}
+	// Applet holds the config GOB for the TrustedApplet, followed by the TrustedApplet ELF.
+	Applet = Region{Name: "TrustedApplet", Block: 0x200000, NumBlocks: 0x400000 - 0x200000}
+	// AppletData is the storage area used by the TrustedApplet for its state.
+	AppletData = Region{Name: "applet data", Block: 0x400000, NumBlocks: 0x400000}
+
+	// Regions lists all regions, in the order in which they appear on the MMC.
+	Regions = []Region{Bootloader, BootloaderConfig, OS, Applet, AppletData}
+)
+
+// TotalSize is the number of bytes of the MMC which are covered by the regions above.
+var TotalSize = AppletData.End() * BlockSize
+
+// Offset returns the offset in bytes of the start of the region.
+func (r Region) Offset() int64 {
+	return r.Block * BlockSize
+}
+
+// Size returns the size of the region in bytes.
+func (r Region) Size() int64 {
+	return r.NumBlocks * BlockSize
+}
+
+// End returns the first block after the region.
+func (r Region) End() int64 {
+	return r.Block + r.NumBlocks
+}
+
+// String returns a human readable description of the region.
+func (r Region) String() string {
+	return fmt.Sprintf("%s [0x%x, 0x%x)", r.Name, r.Block, r.End())
+}
+
+// Contains returns an error unless the size bytes starting at the given MMC byte offset
+// all lie within the region.
+func (r Region) Contains(offset, size int64) error {
+	if offset < r.Offset() || size < 0 || offset+size > r.Offset()+r.Size() {
+		return fmt.Errorf("0x%x bytes at offset 0x%x do not fit in region %s", size, offset, r)
+	}
+	return nil
+}
+
+// ForBlock returns the region which starts at the given block.
+func ForBlock(block int64) (Region, error) {
+	for _, r := range Regions {
+		if r.Block == block {
+			return r, nil
+		}
+	}
+	return Region{}, fmt.Errorf("no region starts at block 0x%x", block)
+}
+
+// CheckWrite returns an error unless size bytes written at the start of the given block
+// lie within the single region starting at that block.
+func CheckWrite(block int64, size int64) error {
+	r, err := ForBlock(block)
+	if err != nil {
+		return err
+	}
+	return r.Contains(r.Offset(), size)
+}
@@ -0,0 +1,67 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package layout
+
+import (
+	"testing"
+)
+
+func TestRegionsContiguous(t *testing.T) {
+	for i := 1; i < len(Regions); i++ {
+		if prev, r := Regions[i-1], Regions[i]; prev.End() != r.Block {
+			t.Errorf("%s does not immediately follow %s", r, prev)
+		}
+	}
+}
+
+func TestCheckWrite(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		block   int64
+		size    int64
+		wantErr bool
+	}{
+		{
+			name:  "OS fits",
+			block: OS.Block,
+			size:  OS.Size(),
+		}, {
+			name:    "OS spills into applet",
+			block:   OS.Block,
+			size:    OS.Size() + 1,
+			wantErr: true,
+		}, {
+			name:    "bootloader overlaps boot config",
+			block:   Bootloader.Block,
+			size:    (BootloaderConfig.Block-Bootloader.Block)*BlockSize + 1,
+			wantErr: true,
+		}, {
+			name:  "boot config fits",
+			block: BootloaderConfig.Block,
+			size:  40960,
+		}, {
+			name:    "not the start of a region",
+			block:   OS.Block + 1,
+			size:    1,
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if err := CheckWrite(test.block, test.size); (err != nil) != test.wantErr {
+				t.Errorf("CheckWrite(0x%x, 0x%x): %v, wantErr %v", test.block, test.size, err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// Response is the answer to a confirm or confirm_typed event, read by the JSONLines operator.
+type Response struct {
+	// ID is the ID of the event being answered.
+	ID uint64 `json:"id"`
+	// Confirm is true if the action may go ahead.
+	Confirm bool `json:"confirm"`
+	// Text is the text typed by the operator in answer to a confirm_typed event.
+	Text string `json:"text,omitempty"`
+}
+
+// JSONLines is an operator which is another program, e.g. a GUI or test harness.
+//
+// Every interaction is written as a JSON encoded Event on a line of its own, and confirm
+// and confirm_typed events are answered by writing a JSON encoded Response on a line of its own.
+type JSONLines struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	nextID  uint64
+	waiting map[uint64]chan Response
+	// closed is true once no more responses can be read.
+	closed bool
+}
+
+// NewJSONLines creates an operator which writes events to out, and reads responses from in.
+func NewJSONLines(in io.Reader, out io.Writer) *JSONLines {
+	j := &JSONLines{
+		enc:     json.NewEncoder(out),
+		nextID:  1,
+		waiting: make(map[uint64]chan Response),
+	}
+	go j.readResponses(in)
+	return j
+}
+
+// Instruct implements Operator.
+func (j *JSONLines) Instruct(device string, i Instruction) {
+	j.write(instructEvent(device, i))
+}
+
+// Confirm implements Operator.
+func (j *JSONLines) Confirm(ctx context.Context, device string, action string) error {
+	r, err := j.ask(ctx, confirmEvent(device, action))
+	if err != nil {
+		return err
+	}
+	if !r.Confirm {
+		return fmt.Errorf("operator declined %q", action)
+	}
+	return nil
+}
+
+// ConfirmTyped implements Operator.
+func (j *JSONLines) ConfirmTyped(ctx context.Context, device string, action string, hint string, want string) error {
+	r, err := j.ask(ctx, confirmTypedEvent(device, action, hint))
+	if err != nil {
+		return err
+	}
+	if !r.Confirm {
+		return fmt.Errorf("operator declined %q", action)
+	}
+	if strings.TrimSpace(r.Text) != want {
+		return fmt.Errorf("operator did not confirm %q", action)
+	}
+	return nil
+}
+
+// ask writes the event e, and waits for the response to it.
+func (j *JSONLines) ask(ctx context.Context, e Event) (Response, error) {
+	c := make(chan Response, 1)
+	j.mu.Lock()
+	if j.closed {
+		j.mu.Unlock()
+		return Response{}, fmt.Errorf("no confirmation can be received for %q", e.Action)
+	}
+	e.ID = j.nextID
+	j.nextID++
+	j.waiting[e.ID] = c
+	j.mu.Unlock()
+	defer func() {
+		j.mu.Lock()
+		delete(j.waiting, e.ID)
+		j.mu.Unlock()
+	}()
+
+	j.write(e)
+	select {
+	case <-ctx.Done():
+		return Response{}, ctx.Err()
+	case r, open := <-c:
+		if !open {
+			return Response{}, fmt.Errorf("no confirmation received for %q", e.Action)
+		}
+		return r, nil
+	}
+}
+
+// Progress implements Operator.
+func (j *JSONLines) Progress(device string, step string, done, total int) {
+	j.write(progressEvent(device, step, done, total))
+}
+
+func (j *JSONLines) write(e Event) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.enc.Encode(e); err != nil {
+		klog.Errorf("Failed to write operator event: %v", err)
+	}
+}
+
+// readResponses passes responses read from r to the corresponding waiting confirmation.
+// Once r is exhausted, all current and future confirmations fail.
+func (j *JSONLines) readResponses(r io.Reader) {
+	dec := json.NewDecoder(r)
+	for {
+		resp := Response{}
+		if err := dec.Decode(&resp); err != nil {
+			if err != io.EOF {
+				klog.Errorf("Failed to read operator response: %v", err)
+			}
+			break
+		}
+		j.mu.Lock()
+		c, ok := j.waiting[resp.ID]
+		delete(j.waiting, resp.ID)
+		j.mu.Unlock()
+		if !ok {
+			klog.Warningf("Ignoring response to unknown or already answered confirmation %d", resp.ID)
+			continue
+		}
+		c <- resp
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.closed = true
+	for id, c := range j.waiting {
+		close(c)
+		delete(j.waiting, id)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package operator provides support for interacting with the person, or program, operating
+// the devices being worked on by the tools.
+package operator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// BootSwitch is a position of the boot switch on the underside of the device.
+type BootSwitch string
+
+const (
+	// BootSwitchAny is used when the position of the boot switch doesn't matter.
+	BootSwitchAny BootSwitch = ""
+	// BootSwitchUSB is the position which causes the device to boot into SDP mode.
+	BootSwitchUSB BootSwitch = "usb"
+	// BootSwitchMMC is the position which causes the device to boot from its MMC.
+	BootSwitchMMC BootSwitch = "mmc"
+)
+
+// Instruction describes something the operator needs to do to a device.
+type Instruction struct {
+	// Switch is the position the boot switch must be set to.
+	Switch BootSwitch
+	// Action is what the operator should do once the switch is set, e.g. "reboot device".
+	Action string
+}
+
+// String returns the instruction in a form suitable for showing to a human.
+func (i Instruction) String() string {
+	switch i.Switch {
+	case BootSwitchUSB:
+		return fmt.Sprintf("please ensure boot switch is set to USB (towards RJ45 socket), and then %s", i.Action)
+	case BootSwitchMMC:
+		return fmt.Sprintf("please ensure boot switch is set to MMC (away from RJ45 socket), and then %s", i.Action)
+	default:
+		return fmt.Sprintf("please %s", i.Action)
+	}
+}
+
+// Operator is the interface through which the tools interact with the operator.
+//
+// The device argument to each method identifies the device concerned when several are
+// being worked on at once, and is empty otherwise.
+type Operator interface {
+	// Instruct asks the operator to do something to a device.
+	// It doesn't wait for the operator to do it, callers are expected to detect the
+	// result for themselves, e.g. by waiting for the device to appear on the USB bus.
+	Instruct(device string, i Instruction)
+	// Confirm waits for the operator to confirm that the described action may go ahead,
+	// and returns an error if they decline or ctx becomes done first.
+	Confirm(ctx context.Context, device string, action string) error
+	// ConfirmTyped is like Confirm, but the operator must confirm by typing want, which
+	// is described to them by hint (e.g. "the last 4 characters of the serial number").
+	// It's intended for irreversible actions, where pressing a key is too easy.
+	ConfirmTyped(ctx context.Context, device string, action string, hint string, want string) error
+	// Progress reports that the named step, which is number done of total, has been reached.
+	Progress(device string, step string, done, total int)
+}
+
+// Kinds lists the names of the operators which can be created with New.
+var Kinds = []string{"tty", "json"}
+
+// New creates an operator of the named kind, which interacts via stdin and stdout.
+func New(kind string) (Operator, error) {
+	switch kind {
+	case "tty":
+		return NewTTY(os.Stdin, os.Stdout), nil
+	case "json":
+		return NewJSONLines(os.Stdin, os.Stdout), nil
+	default:
+		return nil, fmt.Errorf("unknown operator kind %q, must be one of %v", kind, Kinds)
+	}
+}
+
+// Event is a single interaction with the operator, as recorded by the JSONLines and
+// Scripted operators.
+type Event struct {
+	// Time is the time at which the event happened.
+	Time time.Time `json:"time"`
+	// Type is one of "instruct", "confirm", "confirm_typed", or "progress".
+	Type string `json:"type"`
+	// ID identifies a confirm or confirm_typed event, so that the response can be matched
+	// up with it.
+	ID uint64 `json:"id,omitempty"`
+	// Device identifies the device concerned, if several are being worked on at once.
+	Device string `json:"device,omitempty"`
+
+	// Switch and Action are set for instruct events, Action is also set for confirm and
+	// confirm_typed events.
+	Switch BootSwitch `json:"switch,omitempty"`
+	Action string     `json:"action,omitempty"`
+
+	// Hint describes what the operator must type, and is set for confirm_typed events.
+	Hint string `json:"hint,omitempty"`
+
+	// Step, Done, and Total are set for progress events.
+	Step  string `json:"step,omitempty"`
+	Done  int    `json:"done,omitempty"`
+	Total int    `json:"total,omitempty"`
+
+	// Text is a human readable description of the event.
+	Text string `json:"text"`
+}
+
+func instructEvent(device string, i Instruction) Event {
+	return Event{Time: time.Now().UTC(), Type: "instruct", Device: device, Switch: i.Switch, Action: i.Action, Text: i.String()}
+}
+
+func confirmEvent(device string, action string) Event {
+	return Event{Time: time.Now().UTC(), Type: "confirm", Device: device, Action: action, Text: fmt.Sprintf("confirm to continue with: %s", action)}
+}
+
+func confirmTypedEvent(device string, action string, hint string) Event {
+	return Event{Time: time.Now().UTC(), Type: "confirm_typed", Device: device, Action: action, Hint: hint, Text: fmt.Sprintf("type %s to continue with: %s", hint, action)}
+}
+
+func progressEvent(device string, step string, done, total int) Event {
+	return Event{Time: time.Now().UTC(), Type: "progress", Device: device, Step: step, Done: done, Total: total, Text: fmt.Sprintf("[%d/%d] %s", done, total, step)}
+}
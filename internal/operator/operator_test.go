@@ -0,0 +1,139 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestJSONLines(t *testing.T) {
+	ctx := context.Background()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	j := NewJSONLines(inR, outW)
+	events := bufio.NewScanner(outR)
+	next := func() Event {
+		t.Helper()
+		if !events.Scan() {
+			t.Fatalf("No event: %v", events.Err())
+		}
+		e := Event{}
+		if err := json.Unmarshal(events.Bytes(), &e); err != nil {
+			t.Fatalf("Invalid event %q: %v", events.Text(), err)
+		}
+		return e
+	}
+
+	go j.Instruct("1-2", Instruction{Switch: BootSwitchUSB, Action: "connect device"})
+	if e := next(); e.Type != "instruct" || e.Device != "1-2" || e.Switch != BootSwitchUSB || e.Action != "connect device" {
+		t.Errorf("Got instruct event %+v", e)
+	}
+
+	for _, confirm := range []bool{true, false} {
+		errC := make(chan error)
+		go func() { errC <- j.Confirm(ctx, "", "flash") }()
+		e := next()
+		if e.Type != "confirm" || e.ID == 0 {
+			t.Fatalf("Got confirm event %+v", e)
+		}
+		if _, err := fmt.Fprintf(inW, `{"id": %d, "confirm": %v}`+"\n", e.ID, confirm); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := <-errC; (err == nil) != confirm {
+			t.Errorf("Confirm with response %v: %v", confirm, err)
+		}
+	}
+
+	for _, typed := range []string{"a1b2", "a1b3"} {
+		errC := make(chan error)
+		go func() { errC <- j.ConfirmTyped(ctx, "", "fuse", "the serial", "a1b2") }()
+		e := next()
+		if e.Type != "confirm_typed" || e.ID == 0 || e.Hint != "the serial" {
+			t.Fatalf("Got confirm_typed event %+v", e)
+		}
+		if _, err := fmt.Fprintf(inW, `{"id": %d, "confirm": true, "text": %q}`+"\n", e.ID, typed); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := <-errC; (err == nil) != (typed == "a1b2") {
+			t.Errorf("ConfirmTyped with text %q: %v", typed, err)
+		}
+	}
+
+	// Once the input is closed, confirmations must fail rather than hang.
+	errC := make(chan error)
+	go func() { errC <- j.Confirm(ctx, "", "fuse") }()
+	next()
+	_ = inW.Close()
+	if err := <-errC; err == nil {
+		t.Error("Confirm after input closed succeeded, want error")
+	}
+}
+
+func TestTTYConfirmTyped(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		in      string
+		wantErr bool
+	}{
+		{in: "a1b2\n"},
+		{in: " a1b2 \n"},
+		{in: "\n", wantErr: true},
+		{in: "a1b3\n", wantErr: true},
+		{in: "", wantErr: true},
+	} {
+		inR, inW := io.Pipe()
+		// Only type the input once the prompt has been shown, so it isn't discarded as stale.
+		prompted := writerFunc(func(b []byte) (int, error) {
+			go func() {
+				_, _ = io.WriteString(inW, test.in)
+				_ = inW.Close()
+			}()
+			return len(b), nil
+		})
+		tty := NewTTY(inR, prompted)
+		if err := tty.ConfirmTyped(ctx, "", "fuse", "the serial", "a1b2"); (err != nil) != test.wantErr {
+			t.Errorf("ConfirmTyped with input %q: got err %v, want err %t", test.in, err, test.wantErr)
+		}
+	}
+}
+
+func TestScripted(t *testing.T) {
+	ctx := context.Background()
+	s := NewScripted(true, false)
+	s.Progress("", "flash", 1, 2)
+	if err := s.Confirm(ctx, "", "flash"); err != nil {
+		t.Errorf("First Confirm: %v", err)
+	}
+	if err := s.Confirm(ctx, "", "fuse"); err == nil {
+		t.Error("Second Confirm succeeded, want declined")
+	}
+	if err := s.Confirm(ctx, "", "fuse"); err == nil {
+		t.Error("Unscripted Confirm succeeded, want error")
+	}
+	if got, want := len(s.Events()), 4; got != want {
+		t.Errorf("Got %d events, want %d", got, want)
+	}
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(b []byte) (int, error) {
+	return f(b)
+}
@@ -0,0 +1,94 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Scripted is an operator for use in tests, which answers confirmations from a script
+// and records every interaction.
+//
+// Typed confirmations are answered from the same script, a true response being taken
+// to mean that the expected text was typed.
+type Scripted struct {
+	mu        sync.Mutex
+	responses []bool
+	events    []Event
+}
+
+// NewScripted creates an operator which answers successive confirmations with the
+// passed in responses, and fails any confirmations beyond those.
+func NewScripted(responses ...bool) *Scripted {
+	return &Scripted{responses: responses}
+}
+
+// Instruct implements Operator.
+func (s *Scripted) Instruct(device string, i Instruction) {
+	s.record(instructEvent(device, i))
+}
+
+// Confirm implements Operator.
+func (s *Scripted) Confirm(ctx context.Context, device string, action string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.answer(ctx, confirmEvent(device, action))
+}
+
+// ConfirmTyped implements Operator.
+func (s *Scripted) ConfirmTyped(ctx context.Context, device string, action string, hint string, want string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.answer(ctx, confirmTypedEvent(device, action, hint))
+}
+
+// answer records the confirmation event e, and answers it with the next scripted response.
+// s.mu must be held.
+func (s *Scripted) answer(ctx context.Context, e Event) error {
+	e.ID = uint64(len(s.events) + 1)
+	s.events = append(s.events, e)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(s.responses) == 0 {
+		return fmt.Errorf("unscripted confirmation of %q", e.Action)
+	}
+	r := s.responses[0]
+	s.responses = s.responses[1:]
+	if !r {
+		return fmt.Errorf("operator declined %q", e.Action)
+	}
+	return nil
+}
+
+// Progress implements Operator.
+func (s *Scripted) Progress(device string, step string, done, total int) {
+	s.record(progressEvent(device, step, done, total))
+}
+
+// Events returns the interactions which have happened so far.
+func (s *Scripted) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Event(nil), s.events...)
+}
+
+func (s *Scripted) record(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+}
@@ -0,0 +1,130 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+const operPlease = "🔷🔷🔷 🙋 OPERATOR: %s%s 🙏"
+
+// TTY is an operator which is a human sat at a terminal.
+//
+// Instructions and progress are logged, and confirmations are given by pressing Enter,
+// or by typing the requested text.
+type TTY struct {
+	out io.Writer
+
+	// mu serialises confirmations, so that when several devices are being worked on at
+	// once each press of Enter confirms exactly one of them, in the order they were asked.
+	mu    sync.Mutex
+	lines <-chan string
+}
+
+// NewTTY creates an operator which prompts on out, and reads confirmations from in.
+func NewTTY(in io.Reader, out io.Writer) *TTY {
+	return &TTY{out: out, lines: readLines(in)}
+}
+
+// Instruct implements Operator.
+func (t *TTY) Instruct(device string, i Instruction) {
+	klog.Info("------------------------------------------------------------------------------------------------------------")
+	klog.Infof(operPlease, prefix(device), i)
+	klog.Info("------------------------------------------------------------------------------------------------------------")
+}
+
+// Confirm implements Operator.
+func (t *TTY) Confirm(ctx context.Context, device string, action string) error {
+	l, err := t.ask(ctx, fmt.Sprintf("%s%s: press Enter to continue, or type 'no' to stop: ", prefix(device), action))
+	if err != nil {
+		return fmt.Errorf("no confirmation received for %q: %v", action, err)
+	}
+	if a := strings.ToLower(strings.TrimSpace(l)); a != "" && a != "y" && a != "yes" {
+		return fmt.Errorf("operator declined %q", action)
+	}
+	return nil
+}
+
+// ConfirmTyped implements Operator.
+func (t *TTY) ConfirmTyped(ctx context.Context, device string, action string, hint string, want string) error {
+	l, err := t.ask(ctx, fmt.Sprintf("%s%s: type %s to continue: ", prefix(device), action, hint))
+	if err != nil {
+		return fmt.Errorf("no confirmation received for %q: %v", action, err)
+	}
+	if strings.TrimSpace(l) != want {
+		return fmt.Errorf("operator did not confirm %q", action)
+	}
+	return nil
+}
+
+// ask prints the prompt, and returns the next line typed by the operator.
+func (t *TTY) ask(ctx context.Context, prompt string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// Discard anything typed before we asked, so a stray Enter can't confirm an action
+	// the operator hasn't seen yet.
+	for drained := false; !drained; {
+		select {
+		case <-t.lines:
+		default:
+			drained = true
+		}
+	}
+	if _, err := fmt.Fprint(t.out, prompt); err != nil {
+		return "", err
+	}
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case l, ok := <-t.lines:
+		if !ok {
+			return "", io.EOF
+		}
+		return l, nil
+	}
+}
+
+// Progress implements Operator.
+func (t *TTY) Progress(device string, step string, done, total int) {
+	klog.Infof("▶️  %s[%d/%d] %s", prefix(device), done, total, step)
+}
+
+func prefix(device string) string {
+	if device == "" {
+		return ""
+	}
+	return fmt.Sprintf("[%s] ", device)
+}
+
+// readLines returns a channel which yields the lines read from r, and is closed when
+// r is exhausted.
+func readLines(r io.Reader) <-chan string {
+	c := make(chan string)
+	go func() {
+		defer close(c)
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			c <- s.Text()
+		}
+	}()
+	return c
+}
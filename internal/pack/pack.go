@@ -0,0 +1,425 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pack provides support for firmware packs, which hold everything needed to
+// verify and install a set of firmware without access to the firmware transparency log.
+//
+// A pack is a gzipped tar archive containing a directory for each of the boot, recovery,
+// os, and applet components. Each directory holds the following files:
+//
+//   - manifest: the signed manifest, exactly as it's stored in the FT log.
+//   - firmware: the firmware binary.
+//   - hab_signature: the HAB signature, only present for components which have one.
+//   - index: the decimal index of the manifest in the FT log.
+//   - inclusion_proof: the proof of inclusion of the manifest at index under checkpoint,
+//     as one base64 encoded hash per line.
+//   - checkpoint: the FT log checkpoint the inclusion proof is against.
+//
+// Alongside these directories, the leaf_hashes file holds the hashes of every leaf in the
+// FT log up to the size of the largest of the checkpoints, as one base64 encoded hash per
+// line. This allows other checkpoints from the log, such as those stored on a device, to be
+// checked for consistency with the pack without access to the log.
+package pack
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/transparency-dev/armored-witness-common/release/firmware"
+	"github.com/transparency-dev/armored-witness-common/release/firmware/ftlog"
+	"github.com/transparency-dev/armored-witness/internal/policy"
+	"github.com/transparency-dev/armored-witness/internal/release"
+	"github.com/transparency-dev/formats/log"
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"golang.org/x/mod/sumdb/note"
+)
+
+const (
+	fileManifest       = "manifest"
+	fileFirmware       = "firmware"
+	fileHABSignature   = "hab_signature"
+	fileIndex          = "index"
+	fileInclusionProof = "inclusion_proof"
+	fileCheckpoint     = "checkpoint"
+	fileLeafHashes     = "leaf_hashes"
+
+	// maxFileSize is the largest file which will be read from a pack.
+	maxFileSize = 64 << 20
+)
+
+// components lists the FT log component names of the firmware held in a pack, in the
+// order in which they're written.
+var components = []string{ftlog.ComponentBoot, ftlog.ComponentRecovery, ftlog.ComponentOS, ftlog.ComponentApplet}
+
+// dirs maps FT log component names to the directory used for them in a pack.
+var dirs = map[string]string{
+	ftlog.ComponentBoot:     "boot",
+	ftlog.ComponentRecovery: "recovery",
+	ftlog.ComponentOS:       "os",
+	ftlog.ComponentApplet:   "applet",
+}
+
+// Pack holds the firmware bundles for each of the firmware components.
+type Pack struct {
+	Boot     firmware.Bundle
+	Recovery firmware.Bundle
+	OS       firmware.Bundle
+	Applet   firmware.Bundle
+
+	// LeafHashes are the hashes of the leaves in the FT log, up to the size of the largest
+	// bundle checkpoint.
+	LeafHashes [][]byte
+}
+
+// bundles returns pointers to the bundles in the pack, keyed by FT log component name.
+func (p *Pack) bundles() map[string]*firmware.Bundle {
+	return map[string]*firmware.Bundle{
+		ftlog.ComponentBoot:     &p.Boot,
+		ftlog.ComponentRecovery: &p.Recovery,
+		ftlog.ComponentOS:       &p.OS,
+		ftlog.ComponentApplet:   &p.Applet,
+	}
+}
+
+// Write writes the pack as a gzipped tar archive to w.
+func Write(w io.Writer, p *Pack) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	add := func(name string, body []byte) error {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(body)), ModTime: now}); err != nil {
+			return err
+		}
+		_, err := tw.Write(body)
+		return err
+	}
+
+	bs := p.bundles()
+	for _, c := range components {
+		b, d := bs[c], dirs[c]
+		files := []struct {
+			name string
+			body []byte
+		}{
+			{fileManifest, b.Manifest},
+			{fileFirmware, b.Firmware},
+			{fileIndex, []byte(strconv.FormatUint(b.Index, 10))},
+			{fileInclusionProof, encodeHashes(b.InclusionProof)},
+			{fileCheckpoint, b.Checkpoint},
+		}
+		if len(b.HABSignature) > 0 {
+			files = append(files, struct {
+				name string
+				body []byte
+			}{fileHABSignature, b.HABSignature})
+		}
+		for _, f := range files {
+			if err := add(path.Join(d, f.name), f.body); err != nil {
+				return fmt.Errorf("failed to write %s/%s: %v", d, f.name, err)
+			}
+		}
+	}
+	if err := add(fileLeafHashes, encodeHashes(p.LeafHashes)); err != nil {
+		return fmt.Errorf("failed to write %s: %v", fileLeafHashes, err)
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// encodeHashes returns hs as one base64 encoded hash per line.
+func encodeHashes(hs [][]byte) []byte {
+	b := &bytes.Buffer{}
+	for _, h := range hs {
+		fmt.Fprintln(b, base64.StdEncoding.EncodeToString(h))
+	}
+	return b.Bytes()
+}
+
+// decodeHashes is the inverse of encodeHashes.
+func decodeHashes(b []byte) ([][]byte, error) {
+	hs := [][]byte{}
+	for _, l := range strings.Fields(string(b)) {
+		h, err := base64.StdEncoding.DecodeString(l)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hash: %v", err)
+		}
+		hs = append(hs, h)
+	}
+	return hs, nil
+}
+
+// Read reads a pack written by Write from r.
+//
+// Note that the contents of the pack are not verified, callers should use a Verifier to do this.
+func Read(r io.Reader) (*Pack, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pack: %v", err)
+	}
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pack: %v", err)
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		if h.Size > maxFileSize {
+			return nil, fmt.Errorf("%s is too large (%d bytes)", h.Name, h.Size)
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", h.Name, err)
+		}
+		files[path.Clean(h.Name)] = b
+	}
+
+	p := &Pack{}
+	errs := []error{}
+	bs := p.bundles()
+	for _, c := range components {
+		b, d := bs[c], dirs[c]
+		get := func(name string) []byte {
+			f, ok := files[path.Join(d, name)]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s/%s missing from pack", d, name))
+			}
+			return f
+		}
+		b.Manifest = get(fileManifest)
+		b.Firmware = get(fileFirmware)
+		b.Checkpoint = get(fileCheckpoint)
+		b.HABSignature = files[path.Join(d, fileHABSignature)]
+		if i := get(fileIndex); i != nil {
+			if b.Index, err = strconv.ParseUint(strings.TrimSpace(string(i)), 10, 64); err != nil {
+				errs = append(errs, fmt.Errorf("%s/%s: invalid index: %v", d, fileIndex, err))
+			}
+		}
+		if ip := get(fileInclusionProof); ip != nil {
+			if b.InclusionProof, err = decodeHashes(ip); err != nil {
+				errs = append(errs, fmt.Errorf("%s/%s: %v", d, fileInclusionProof, err))
+			}
+		}
+	}
+	if lh, ok := files[fileLeafHashes]; !ok {
+		errs = append(errs, fmt.Errorf("%s missing from pack", fileLeafHashes))
+	} else if p.LeafHashes, err = decodeHashes(lh); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %v", fileLeafHashes, err))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Verifier knows how to verify the contents of a pack.
+type Verifier struct {
+	// LogOrigin is the origin of the FT log the firmware must be logged in.
+	LogOrigin string
+	// LogVerifier verifies checkpoints from the FT log.
+	LogVerifier note.Verifier
+	// ManifestVerifiers holds the verifiers which must all have signed the manifest
+	// for each component, keyed by FT log component name.
+	ManifestVerifiers map[string][]note.Verifier
+	// HABTarget, if set, is the HAB target the boot and recovery firmware must be for.
+	HABTarget string
+	// SRKHash, if set, is the hex encoded SRK hash that firmware which was built with
+	// an SRK hash must have been built with.
+	SRKHash string
+	// WitnessPolicy, if set, must be satisfied by the checkpoint in each bundle.
+	WitnessPolicy *policy.Policy
+}
+
+// VerifierFromFlags creates a Verifier from the values of the release template flags in fs,
+// i.e. firmware_log_origin, firmware_log_verifier, the manifest verifier flags, hab_target,
+// and hab_srk_hash. Checkpoints must also satisfy wp, if it's not nil.
+func VerifierFromFlags(fs release.Flags, wp *policy.Policy) (*Verifier, error) {
+	errs := []error{}
+	get := func(name string) string {
+		v, ok := fs.Lookup(name)
+		if !ok {
+			errs = append(errs, fmt.Errorf("flag --%s unknown", name))
+		}
+		return v
+	}
+	verifier := func(name string) note.Verifier {
+		v, err := note.NewVerifier(get(name))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid --%s: %v", name, err))
+		}
+		return v
+	}
+	v := &Verifier{
+		LogOrigin:   get("firmware_log_origin"),
+		LogVerifier: verifier("firmware_log_verifier"),
+		ManifestVerifiers: map[string][]note.Verifier{
+			ftlog.ComponentApplet:   {verifier("applet_verifier")},
+			ftlog.ComponentBoot:     {verifier("boot_verifier")},
+			ftlog.ComponentOS:       {verifier("os_verifier_1"), verifier("os_verifier_2")},
+			ftlog.ComponentRecovery: {verifier("recovery_verifier")},
+		},
+		HABTarget:     get("hab_target"),
+		SRKHash:       get("hab_srk_hash"),
+		WitnessPolicy: wp,
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Verify checks that every bundle in the pack is for the expected component, is correctly
+// signed, is included in the FT log, and matches its firmware and HAB signature, and that
+// the pack's leaf hashes match all of the bundle checkpoints.
+//
+// Returns the parsed manifests, keyed by FT log component name.
+func (v *Verifier) Verify(p *Pack) (map[string]*ftlog.FirmwareRelease, error) {
+	r := make(map[string]*ftlog.FirmwareRelease)
+	errs := []error{}
+	bs := p.bundles()
+	for _, c := range components {
+		m, err := v.VerifyBundle(c, *bs[c])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", dirs[c], err))
+			continue
+		}
+		r[c] = m
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	cp, err := v.Checkpoint(p)
+	if err != nil {
+		return nil, err
+	}
+	if got := uint64(len(p.LeafHashes)); got != cp.Size {
+		return nil, fmt.Errorf("pack has %d leaf hashes, but its checkpoint is for a log of size %d", got, cp.Size)
+	}
+	for _, c := range components {
+		bcp, _, _, err := log.ParseCheckpoint(bs[c].Checkpoint, v.LogOrigin, v.LogVerifier)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid checkpoint: %v", dirs[c], err)
+		}
+		if err := p.CheckConsistency(bcp.Size, bcp.Hash); err != nil {
+			return nil, fmt.Errorf("%s: checkpoint: %v", dirs[c], err)
+		}
+	}
+	return r, nil
+}
+
+// Checkpoint returns the largest of the checkpoints in the pack's bundles, which is the
+// checkpoint the pack's leaf hashes are for.
+func (v *Verifier) Checkpoint(p *Pack) (*log.Checkpoint, error) {
+	var r *log.Checkpoint
+	// All bundles in a pack written by the export tool share the same checkpoint, but
+	// the largest is used in case the pack was assembled some other way.
+	for _, c := range components {
+		cp, _, _, err := log.ParseCheckpoint(p.bundles()[c].Checkpoint, v.LogOrigin, v.LogVerifier)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid checkpoint: %v", dirs[c], err)
+		}
+		if r == nil || cp.Size > r.Size {
+			r = cp
+		}
+	}
+	return r, nil
+}
+
+// CheckConsistency checks that a checkpoint from the FT log for a log of the given size
+// and root hash is consistent with the pack, by recomputing its root hash from the pack's
+// leaf hashes.
+//
+// The pack must have been verified, so that its leaf hashes are known to be correct.
+func (p *Pack) CheckConsistency(size uint64, root []byte) error {
+	if size > uint64(len(p.LeafHashes)) {
+		return fmt.Errorf("checkpoint(@%d) is for a larger log than the firmware pack(@%d), a newer firmware pack is needed", size, len(p.LeafHashes))
+	}
+	rf := compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren}
+	r := rf.NewEmptyRange(0)
+	for _, h := range p.LeafHashes[:size] {
+		if err := r.Append(h, nil); err != nil {
+			return err
+		}
+	}
+	want, err := r.GetRootHash(nil)
+	if err != nil {
+		return fmt.Errorf("failed to compute root hash: %v", err)
+	}
+	if !bytes.Equal(root, want) {
+		return fmt.Errorf("checkpoint(@%d) has root hash %x, but the firmware pack's leaves give %x", size, root, want)
+	}
+	return nil
+}
+
+// VerifyBundle checks that a single bundle is for the given component, is correctly signed,
+// is included in the FT log, and matches its firmware and HAB signature.
+//
+// Returns the parsed manifest.
+func (v *Verifier) VerifyBundle(component string, b firmware.Bundle) (*ftlog.FirmwareRelease, error) {
+	mv := v.ManifestVerifiers[component]
+	if len(mv) == 0 {
+		return nil, errors.New("no manifest verifiers")
+	}
+	bv := firmware.BundleVerifier{
+		LogOrigin:         v.LogOrigin,
+		LogVerifer:        v.LogVerifier,
+		ManifestVerifiers: mv,
+	}
+	m, err := bv.Verify(b)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.WitnessPolicy.Check(b.Checkpoint); err != nil {
+		return nil, fmt.Errorf("proof bundle checkpoint: %v", err)
+	}
+	if m.Component != component {
+		return nil, fmt.Errorf("manifest is for component %q", m.Component)
+	}
+	isHABComponent := component == ftlog.ComponentBoot || component == ftlog.ComponentRecovery
+	if isHABComponent && v.HABTarget != "" && (m.HAB == nil || m.HAB.Target != v.HABTarget) {
+		return nil, fmt.Errorf("manifest is not for HAB target %q", v.HABTarget)
+	}
+	if v.SRKHash != "" {
+		if err := release.CheckSRKHash(m, v.SRKHash); err != nil {
+			return nil, err
+		}
+	}
+	if m.HAB != nil && len(m.HAB.SignatureDigestSha256) > 0 {
+		if h := sha256.Sum256(b.HABSignature); !bytes.Equal(h[:], m.HAB.SignatureDigestSha256) {
+			return nil, fmt.Errorf("HAB signature hash mismatch: manifest says %x but signature bytes hash to %x", m.HAB.SignatureDigestSha256, h)
+		}
+	} else if len(b.HABSignature) > 0 {
+		return nil, errors.New("bundle contains a HAB signature which is not committed to by the manifest")
+	}
+	return m, nil
+}
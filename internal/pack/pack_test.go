@@ -0,0 +1,142 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pack
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"reflect"
+	"testing"
+
+	"github.com/transparency-dev/armored-witness-common/release/firmware"
+	"github.com/transparency-dev/armored-witness-common/release/firmware/ftlog"
+	"github.com/transparency-dev/armored-witness/internal/release"
+	"github.com/transparency-dev/merkle/rfc6962"
+)
+
+func testBundle(name string, i uint64, habSig bool) firmware.Bundle {
+	b := firmware.Bundle{
+		Checkpoint:     []byte("origin\n42\nroot\n\n— sig\n"),
+		Index:          i,
+		InclusionProof: [][]byte{bytes.Repeat([]byte{byte(i)}, 32), bytes.Repeat([]byte{byte(i + 1)}, 32)},
+		Manifest:       []byte(fmt.Sprintf("%s manifest", name)),
+		Firmware:       []byte(fmt.Sprintf("%s firmware", name)),
+	}
+	if habSig {
+		b.HABSignature = []byte(fmt.Sprintf("%s HAB signature", name))
+	}
+	return b
+}
+
+func TestRoundTrip(t *testing.T) {
+	want := &Pack{
+		Boot:     testBundle("boot", 1, true),
+		Recovery: testBundle("recovery", 2, true),
+		OS:       testBundle("os", 3, false),
+		Applet:   testBundle("applet", 4, false),
+
+		LeafHashes: testLeafHashes(5),
+	}
+	buf := &bytes.Buffer{}
+	if err := Write(buf, want); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	got, err := Read(buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %+v, want %+v", got, want)
+	}
+}
+
+type testFlags map[string]string
+
+func (f testFlags) Lookup(name string) (string, bool) {
+	v, ok := f[name]
+	return v, ok
+}
+
+func (f testFlags) Set(name, value string) error {
+	f[name] = value
+	return nil
+}
+
+func TestVerifierFromFlags(t *testing.T) {
+	newFlags := func() testFlags {
+		return testFlags(maps.Clone(release.Templates["ci"]))
+	}
+
+	v, err := VerifierFromFlags(newFlags(), nil)
+	if err != nil {
+		t.Fatalf("VerifierFromFlags: %v", err)
+	}
+	if got, want := v.LogOrigin, release.Templates["ci"]["firmware_log_origin"]; got != want {
+		t.Errorf("LogOrigin = %q, want %q", got, want)
+	}
+	if got, want := v.HABTarget, "ci"; got != want {
+		t.Errorf("HABTarget = %q, want %q", got, want)
+	}
+	if got := len(v.ManifestVerifiers[ftlog.ComponentOS]); got != 2 {
+		t.Errorf("Got %d OS verifiers, want 2", got)
+	}
+
+	invalid := newFlags()
+	invalid["boot_verifier"] = "not a verifier"
+	if _, err := VerifierFromFlags(invalid, nil); err == nil {
+		t.Error("VerifierFromFlags with invalid verifier: want error, got none")
+	}
+	missing := newFlags()
+	delete(missing, "hab_srk_hash")
+	if _, err := VerifierFromFlags(missing, nil); err == nil {
+		t.Error("VerifierFromFlags with missing flag: want error, got none")
+	}
+}
+
+func testLeafHashes(n int) [][]byte {
+	r := [][]byte{}
+	for i := range n {
+		r = append(r, rfc6962.DefaultHasher.HashLeaf([]byte(fmt.Sprintf("leaf %d", i))))
+	}
+	return r
+}
+
+func TestCheckConsistency(t *testing.T) {
+	h := rfc6962.DefaultHasher
+	lh := testLeafHashes(3)
+	p := &Pack{LeafHashes: lh}
+	root2 := h.HashChildren(lh[0], lh[1])
+	root3 := h.HashChildren(root2, lh[2])
+
+	for _, test := range []struct {
+		desc    string
+		size    uint64
+		root    []byte
+		wantErr bool
+	}{
+		{desc: "one leaf", size: 1, root: lh[0]},
+		{desc: "two leaves", size: 2, root: root2},
+		{desc: "whole pack", size: 3, root: root3},
+		{desc: "wrong root", size: 2, root: root3, wantErr: true},
+		{desc: "larger than pack", size: 4, root: root3, wantErr: true},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if err := p.CheckConsistency(test.size, test.root); (err != nil) != test.wantErr {
+				t.Errorf("CheckConsistency(%d): got err %v, want err %t", test.size, err, test.wantErr)
+			}
+		})
+	}
+}
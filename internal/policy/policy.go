@@ -0,0 +1,133 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package policy checks that FT log checkpoints have been cosigned by enough witnesses.
+//
+// Checkpoints are otherwise only signed by the log itself, which could present different
+// views of the log to different clients. Witnesses only cosign checkpoints which are
+// consistent with those they've seen before, so requiring cosignatures from several
+// independent witnesses makes such a split view much harder to hide.
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/transparency-dev/formats/log"
+	"github.com/transparency-dev/formats/witness"
+	"github.com/transparency-dev/serverless-log/client"
+	"golang.org/x/mod/sumdb/note"
+)
+
+// Policy requires checkpoints to be cosigned by at least a threshold number of a set of
+// witnesses.
+//
+// A nil *Policy accepts all checkpoints.
+type Policy struct {
+	witnesses []witness.Witness
+	group     witness.Group
+}
+
+// New creates a policy requiring checkpoints to be cosigned by at least threshold of the
+// witnesses with the given note verifier strings.
+func New(verifiers []string, threshold int) (*Policy, error) {
+	if len(verifiers) == 0 {
+		return nil, errors.New("no witness verifiers")
+	}
+	if threshold < 1 || threshold > len(verifiers) {
+		return nil, fmt.Errorf("witness threshold %d must be between 1 and the number of witnesses, %d", threshold, len(verifiers))
+	}
+	p := &Policy{group: witness.Group{N: threshold}}
+	names := map[string]bool{}
+	// The policy is only used to check cosignatures, so the witnesses' URLs don't matter.
+	for _, v := range verifiers {
+		w, err := witness.New(v, &url.URL{})
+		if err != nil {
+			return nil, fmt.Errorf("invalid witness verifier %q: %v", v, err)
+		}
+		// Otherwise the same witness could be counted twice towards the threshold.
+		if names[w.Key.Name()] {
+			return nil, fmt.Errorf("witness %q is listed more than once", w.Key.Name())
+		}
+		names[w.Key.Name()] = true
+		p.witnesses = append(p.witnesses, w)
+		p.group.Components = append(p.group.Components, w)
+	}
+	return p, nil
+}
+
+// FromFlags creates a policy from a comma separated list of witness verifier strings and a
+// threshold. Whitespace around each verifier string is ignored. Returns a nil policy, which
+// accepts all checkpoints, if verifiers is empty.
+func FromFlags(verifiers string, threshold int) (*Policy, error) {
+	vs := []string{}
+	for _, v := range strings.Split(verifiers, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			vs = append(vs, v)
+		}
+	}
+	if len(vs) == 0 {
+		if threshold != 0 {
+			return nil, errors.New("witness threshold set without any witness verifiers")
+		}
+		return nil, nil
+	}
+	return New(vs, threshold)
+}
+
+// String describes the policy.
+func (p *Policy) String() string {
+	if p == nil {
+		return "no witnesses required"
+	}
+	return fmt.Sprintf("%d of %d witnesses", p.group.N, len(p.witnesses))
+}
+
+// Check returns an error unless the signed checkpoint cp has enough witness cosignatures.
+func (p *Policy) Check(cp []byte) error {
+	if p == nil || p.group.Satisfied(cp) {
+		return nil
+	}
+	signed := []string{}
+	for _, w := range p.witnesses {
+		if w.Satisfied(cp) {
+			signed = append(signed, w.Key.Name())
+		}
+	}
+	return fmt.Errorf("checkpoint is cosigned by %d witnesses %q, but %s are required", len(signed), signed, p)
+}
+
+// Consensus returns a function which fetches the latest checkpoint from the log using f,
+// and fails unless it satisfies the policy.
+//
+// This can be used in place of client.UnilateralConsensus, which trusts the log alone.
+func (p *Policy) Consensus(f client.Fetcher) client.ConsensusCheckpointFunc {
+	fetch := client.UnilateralConsensus(f)
+	if p == nil {
+		return fetch
+	}
+	return func(ctx context.Context, logSigV note.Verifier, origin string) (*log.Checkpoint, []byte, *note.Note, error) {
+		cp, cpRaw, n, err := fetch(ctx, logSigV, origin)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if err := p.Check(cpRaw); err != nil {
+			return nil, nil, nil, fmt.Errorf("log checkpoint(@%d) does not satisfy witness policy: %v", cp.Size, err)
+		}
+		return cp, cpRaw, n, nil
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+
+	f_note "github.com/transparency-dev/formats/note"
+	"golang.org/x/mod/sumdb/note"
+)
+
+const origin = "example.com/log"
+
+// signCheckpoint returns a checkpoint signed by the log, and cosigned by each of the
+// witnesses.
+func signCheckpoint(t *testing.T, logKey string, witnessKeys ...string) []byte {
+	t.Helper()
+	s, err := note.NewSigner(logKey)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	signers := []note.Signer{s}
+	for _, k := range witnessKeys {
+		w, err := f_note.NewSignerForCosignatureV1(k)
+		if err != nil {
+			t.Fatalf("NewSignerForCosignatureV1: %v", err)
+		}
+		signers = append(signers, w)
+	}
+	cp, err := note.Sign(&note.Note{Text: origin + "\n3\nqINS1GRFhWHwdkUeqLEoP4yEMkTBBzxBkGwGQlVlVcs=\n"}, signers...)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return cp
+}
+
+func generateKey(t *testing.T, name string) (string, string) {
+	t.Helper()
+	skey, vkey, err := note.GenerateKey(rand.Reader, name)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return skey, vkey
+}
+
+func TestCheck(t *testing.T) {
+	logS, _ := generateKey(t, "log")
+	w1S, w1V := generateKey(t, "w1")
+	w2S, w2V := generateKey(t, "w2")
+	w3S, _ := generateKey(t, "w3")
+
+	for _, test := range []struct {
+		name      string
+		threshold int
+		cosigners []string
+		wantErr   bool
+	}{
+		{name: "1 of 2 satisfied", threshold: 1, cosigners: []string{w2S}},
+		{name: "2 of 2 satisfied", threshold: 2, cosigners: []string{w1S, w2S}},
+		{name: "2 of 2 with one cosignature", threshold: 2, cosigners: []string{w1S}, wantErr: true},
+		{name: "no cosignatures", threshold: 1, wantErr: true},
+		{name: "unknown witness", threshold: 1, cosigners: []string{w3S}, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := New([]string{w1V, w2V}, test.threshold)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			err = p.Check(signCheckpoint(t, logS, test.cosigners...))
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("Check: %v, wantErr %t", err, test.wantErr)
+			}
+		})
+	}
+
+	var p *Policy
+	if err := p.Check(signCheckpoint(t, logS)); err != nil {
+		t.Errorf("nil policy Check: %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, w1V := generateKey(t, "w1")
+	_, w2V := generateKey(t, "w2")
+	for _, test := range []struct {
+		name      string
+		verifiers []string
+		threshold int
+		wantErr   bool
+	}{
+		{name: "ok", verifiers: []string{w1V, w2V}, threshold: 2},
+		{name: "no witnesses", threshold: 1, wantErr: true},
+		{name: "zero threshold", verifiers: []string{w1V}, wantErr: true},
+		{name: "threshold too high", verifiers: []string{w1V, w2V}, threshold: 3, wantErr: true},
+		{name: "duplicate witness", verifiers: []string{w1V, w1V}, threshold: 2, wantErr: true},
+		{name: "bad verifier", verifiers: []string{"w3+12345678+AAAA"}, threshold: 1, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := New(test.verifiers, test.threshold); (err != nil) != test.wantErr {
+				t.Fatalf("New: %v, wantErr %t", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestFromFlags(t *testing.T) {
+	_, w1V := generateKey(t, "w1")
+	_, w2V := generateKey(t, "w2")
+	if p, err := FromFlags("", 0); p != nil || err != nil {
+		t.Errorf("FromFlags with no witnesses = %v, %v, want nil policy", p, err)
+	}
+	if _, err := FromFlags("", 1); err == nil {
+		t.Error("FromFlags with threshold but no witnesses succeeded")
+	}
+	p, err := FromFlags(w1V+","+w2V, 1)
+	if err != nil {
+		t.Fatalf("FromFlags: %v", err)
+	}
+	if got, want := p.String(), "1 of 2 witnesses"; got != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+	p, err = FromFlags(" "+w1V+" , "+w2V+",", 2)
+	if err != nil {
+		t.Fatalf("FromFlags with spaces: %v", err)
+	}
+	if got, want := p.String(), "2 of 2 witnesses"; got != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+}
+
+func TestConsensus(t *testing.T) {
+	logS, logV := generateKey(t, "log")
+	w1S, w1V := generateKey(t, "w1")
+	lv, err := note.NewVerifier(logV)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	p, err := New([]string{w1V}, 1)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	for _, test := range []struct {
+		name    string
+		cp      []byte
+		wantErr bool
+	}{
+		{name: "cosigned", cp: signCheckpoint(t, logS, w1S)},
+		{name: "not cosigned", cp: signCheckpoint(t, logS), wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			f := func(_ context.Context, path string) ([]byte, error) {
+				return test.cp, nil
+			}
+			cp, _, _, err := p.Consensus(f)(context.Background(), lv, origin)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("Consensus: %v, wantErr %t", err, test.wantErr)
+			}
+			if err == nil && cp.Size != 3 {
+				t.Errorf("Got checkpoint size %d, want 3", cp.Size)
+			}
+		})
+	}
+}
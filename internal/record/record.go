@@ -0,0 +1,110 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package record provides support for the signed records produced when a device
+// is provisioned.
+//
+// A record is a note whose text is formed of 2 lines:
+//
+//  1. A line with the text "ArmoredWitness provisioning record v1".
+//  2. A JSON encoded Record.
+//
+// The note is signed by the operator who provisioned the device.
+package record
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/transparency-dev/armored-witness/devices"
+	"golang.org/x/mod/sumdb/note"
+)
+
+// Header is the first line of the text of a provisioning record note.
+const Header = "ArmoredWitness provisioning record v1"
+
+// Indices holds the FT log indices of the firmware installed on a device.
+type Indices struct {
+	Boot     uint64 `json:"boot"`
+	Recovery uint64 `json:"recovery"`
+	OS       uint64 `json:"os"`
+	Applet   uint64 `json:"applet"`
+}
+
+// Record describes a device, and the firmware it was provisioned with.
+type Record struct {
+	// Serial is the serial number of the device.
+	Serial string `json:"serial"`
+	// SRKHash is the SRK hash reported by the device.
+	SRKHash string `json:"srk_hash"`
+	// HAB is true if the device reported that HAB is enabled.
+	HAB bool `json:"hab"`
+	// HABTarget is the release environment the firmware was targetting.
+	HABTarget string `json:"hab_target"`
+	// FuseAuthorization is the signed fuse authorization which permitted the device to
+	// be fused, if it was fused during provisioning.
+	FuseAuthorization string `json:"fuse_authorization,omitempty"`
+
+	// WitnessIdentity is the note verifier string for the device's witness identity.
+	WitnessIdentity string `json:"witness_identity"`
+	// IDAttestPublicKey is the note verifier string for the key the device uses to
+	// attest to its identities.
+	IDAttestPublicKey string `json:"id_attest_public_key"`
+	// AttestedID is the signed note attesting to the device's witness identity.
+	AttestedID string `json:"attested_id"`
+	// AttestedBastionID is the signed note attesting to the device's bastion identity.
+	AttestedBastionID string `json:"attested_bastion_id,omitempty"`
+
+	// Firmware identifies the firmware installed on the device.
+	Firmware Indices `json:"firmware"`
+	// Checkpoint is the FT log checkpoint the firmware was verified against.
+	Checkpoint string `json:"checkpoint"`
+
+	// Time is the time at which provisioning completed.
+	Time time.Time `json:"time"`
+}
+
+// Sign returns the record as a note signed by s.
+func Sign(r *Record, s note.Signer) ([]byte, error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal record: %v", err)
+	}
+	return note.Sign(&note.Note{Text: fmt.Sprintf("%s\n%s\n", Header, b)}, s)
+}
+
+// Open verifies the signature on a record note using v, and returns the record it contains.
+func Open(b []byte, v note.Verifiers) (*Record, error) {
+	n, err := note.Open(b, v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open record: %v", err)
+	}
+	lines := strings.SplitN(n.Text, "\n", 3)
+	if len(lines) != 3 || lines[0] != Header || lines[2] != "" {
+		return nil, fmt.Errorf("invalid record")
+	}
+	r := &Record{}
+	if err := json.Unmarshal([]byte(lines[1]), r); err != nil {
+		return nil, fmt.Errorf("failed to parse record: %v", err)
+	}
+	return r, nil
+}
+
+// DeviceFiles returns the files, keyed by file name, which describe the device in the
+// format used by the devices package.
+func (r *Record) DeviceFiles() (map[string][]byte, error) {
+	return devices.Layout(r.Serial, r.IDAttestPublicKey, r.AttestedID, r.AttestedBastionID)
+}
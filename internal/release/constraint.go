@@ -0,0 +1,184 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/go-semver/semver"
+)
+
+// Constraint is a set of semantic version ranges, used to select a firmware release.
+//
+// Constraints are written as one or more alternatives separated by "||", each of which
+// is a list of terms separated by spaces or commas, all of which must match. A term is
+// one of:
+//   - a version, optionally preceded by one of =, !=, <, <=, >, or >=, e.g. "<=1.2.0"
+//   - a version with wildcards in place of the minor or patch numbers, e.g. "0.4.x",
+//     "1.*", or just "0.4", matching any version with the given prefix, or just "*"
+//     to match any version
+//   - ~ and a version, matching patch releases from that version onwards, e.g. "~1.2.3"
+//     matches ">=1.2.3 <1.3.0"
+//   - ^ and a version, matching releases which are compatible with it, e.g. "^1.2.3"
+//     matches ">=1.2.3 <2.0.0", and "^0.4.1" matches ">=0.4.1 <0.5.0"
+type Constraint struct {
+	s    string
+	alts [][]term
+}
+
+type term struct {
+	op string
+	v  semver.Version
+}
+
+func (t term) matches(v semver.Version) bool {
+	c := v.Compare(t.v)
+	switch t.op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "*":
+		return true
+	}
+	return false
+}
+
+// ParseConstraint parses a version constraint, as described by Constraint.
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{s: s}
+	for _, alt := range strings.Split(s, "||") {
+		terms := []term{}
+		for _, f := range strings.FieldsFunc(alt, func(r rune) bool { return r == ',' || r == ' ' }) {
+			t, err := parseTerm(f)
+			if err != nil {
+				return nil, fmt.Errorf("invalid constraint %q: %v", s, err)
+			}
+			terms = append(terms, t...)
+		}
+		if len(terms) == 0 {
+			return nil, fmt.Errorf("invalid constraint %q: empty range", s)
+		}
+		c.alts = append(c.alts, terms)
+	}
+	return c, nil
+}
+
+// Matches returns true if v satisfies the constraint.
+func (c *Constraint) Matches(v semver.Version) bool {
+	for _, alt := range c.alts {
+		ok := true
+		for _, t := range alt {
+			ok = ok && t.matches(v)
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Constraint) String() string {
+	return c.s
+}
+
+// parseTerm parses a single constraint term, returning the equivalent comparisons.
+func parseTerm(s string) ([]term, error) {
+	op := ""
+	for _, o := range []string{"!=", "<=", ">=", "=", "<", ">", "~", "^"} {
+		if strings.HasPrefix(s, o) {
+			op, s = o, strings.TrimPrefix(s, o)
+			break
+		}
+	}
+	s = strings.TrimPrefix(s, "v")
+
+	// Wildcards and partial versions are only allowed without an operator.
+	parts := strings.SplitN(s, ".", 3)
+	nums := []int64{}
+	for i, p := range parts {
+		if isWildcard(p) {
+			for _, q := range parts[i:] {
+				if !isWildcard(q) {
+					return nil, fmt.Errorf("invalid version %q", s)
+				}
+			}
+			break
+		}
+		if len(nums) == 2 {
+			// The patch number and anything after it is parsed as a full version below.
+			nums = append(nums, 0)
+			break
+		}
+		n, err := strconv.ParseInt(p, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version %q", s)
+		}
+		nums = append(nums, n)
+	}
+	if len(nums) < 3 {
+		if op != "" {
+			return nil, fmt.Errorf("%q cannot be used with a partial version %q", op, s)
+		}
+		switch len(nums) {
+		case 0:
+			return []term{{op: "*"}}, nil
+		case 1:
+			return between(semver.Version{Major: nums[0]}, semver.Version{Major: nums[0] + 1}), nil
+		default:
+			return between(semver.Version{Major: nums[0], Minor: nums[1]}, semver.Version{Major: nums[0], Minor: nums[1] + 1}), nil
+		}
+	}
+
+	v, err := semver.NewVersion(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %q: %v", s, err)
+	}
+	switch op {
+	case "":
+		return []term{{op: "=", v: *v}}, nil
+	case "~":
+		return between(*v, semver.Version{Major: v.Major, Minor: v.Minor + 1}), nil
+	case "^":
+		switch {
+		case v.Major > 0:
+			return between(*v, semver.Version{Major: v.Major + 1}), nil
+		case v.Minor > 0:
+			return between(*v, semver.Version{Minor: v.Minor + 1}), nil
+		default:
+			return between(*v, semver.Version{Patch: v.Patch + 1}), nil
+		}
+	}
+	return []term{{op: op, v: *v}}, nil
+}
+
+func isWildcard(s string) bool {
+	return s == "x" || s == "X" || s == "*"
+}
+
+// between returns terms matching versions in the range [lo, hi).
+func between(lo, hi semver.Version) []term {
+	return []term{{op: ">=", v: lo}, {op: "<", v: hi}}
+}
@@ -0,0 +1,81 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"testing"
+
+	"github.com/coreos/go-semver/semver"
+)
+
+func TestConstraint(t *testing.T) {
+	for _, test := range []struct {
+		c       string
+		match   []string
+		nomatch []string
+	}{
+		{c: "1.2.3", match: []string{"1.2.3"}, nomatch: []string{"1.2.4", "1.2.3-rc1"}},
+		{c: "=v1.2.3", match: []string{"1.2.3"}, nomatch: []string{"1.2.2"}},
+		{c: "!=1.2.3", match: []string{"1.2.2", "1.2.4"}, nomatch: []string{"1.2.3"}},
+		{c: "<=1.2.0", match: []string{"0.9.9", "1.2.0"}, nomatch: []string{"1.2.1"}},
+		{c: "<1.2.0", match: []string{"1.1.9"}, nomatch: []string{"1.2.0"}},
+		{c: ">1.2.0", match: []string{"1.2.1"}, nomatch: []string{"1.2.0"}},
+		{c: ">=1.2.0", match: []string{"1.2.0", "2.0.0"}, nomatch: []string{"1.1.0"}},
+		{c: "0.4.x", match: []string{"0.4.0", "0.4.17"}, nomatch: []string{"0.3.9", "0.5.0"}},
+		{c: "0.4", match: []string{"0.4.3"}, nomatch: []string{"0.5.0"}},
+		{c: "1.*", match: []string{"1.0.0", "1.9.3"}, nomatch: []string{"0.9.0", "2.0.0"}},
+		{c: "*", match: []string{"0.0.1", "12.3.4"}},
+		{c: "~1.2.3", match: []string{"1.2.3", "1.2.9"}, nomatch: []string{"1.2.2", "1.3.0"}},
+		{c: "^1.2.3", match: []string{"1.2.3", "1.9.0"}, nomatch: []string{"1.2.2", "2.0.0"}},
+		{c: "^0.4.1", match: []string{"0.4.1", "0.4.9"}, nomatch: []string{"0.4.0", "0.5.0"}},
+		{c: "^0.0.3", match: []string{"0.0.3"}, nomatch: []string{"0.0.4"}},
+		{c: ">=1.0.0, <1.4.0 !=1.2.0", match: []string{"1.0.0", "1.3.9"}, nomatch: []string{"1.2.0", "1.4.0"}},
+		{c: "0.3.x || >=0.5.0", match: []string{"0.3.1", "0.5.0"}, nomatch: []string{"0.4.0"}},
+	} {
+		t.Run(test.c, func(t *testing.T) {
+			c, err := ParseConstraint(test.c)
+			if err != nil {
+				t.Fatalf("ParseConstraint: %v", err)
+			}
+			for _, v := range test.match {
+				if !c.Matches(*semver.New(v)) {
+					t.Errorf("Matches(%s) = false, want true", v)
+				}
+			}
+			for _, v := range test.nomatch {
+				if c.Matches(*semver.New(v)) {
+					t.Errorf("Matches(%s) = true, want false", v)
+				}
+			}
+		})
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	for _, c := range []string{
+		"",
+		"1.2.3 ||",
+		"one",
+		"1.x.3",
+		"<=1.2",
+		"~1.x",
+		"1.2.3.4",
+		"-1.0.0",
+	} {
+		if _, err := ParseConstraint(c); err == nil {
+			t.Errorf("ParseConstraint(%q): want error, got none", c)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/mod/sumdb/note"
+	"k8s.io/klog/v2"
+)
+
+// TemplateSource describes where release templates may be found in addition to the
+// compiled-in Templates.
+//
+// Template files are JSON objects mapping template names to templates, e.g.:
+//
+//	{
+//	  "staging": {
+//	    "firmware_log_url": "https://example.com/log/",
+//	    ...
+//	  }
+//	}
+//
+// Since templates decide which keys firmware must be signed with, template files are
+// only used if they're either pinned by hash or signed.
+//
+// Only JSON is supported, not YAML. Templates are flat maps of strings, which gain nothing
+// from YAML, whereas YAML's implicit typing can silently change unquoted values (e.g. a
+// hex hash made only of digits, or "no"), and a YAML parser would be a new dependency of
+// every tool which handles signing keys. Files with a .yaml or .yml extension are rejected,
+// rather than failing to parse with a confusing error.
+type TemplateSource struct {
+	// Path is a template file, or a directory whose *.json files are all template files.
+	// If empty, only the compiled-in templates are available.
+	Path string
+	// SHA256 is a list of hex encoded SHA256 hashes, one of which each template file
+	// must match.
+	SHA256 []string
+	// Verifier, if set, is a note verifier string. Each template file must then be a note
+	// signed by this verifier whose text is the JSON templates.
+	Verifier string
+}
+
+// Templates returns the compiled-in templates along with any loaded from s.
+func (s TemplateSource) Templates() (map[string]Template, error) {
+	ts := maps.Clone(Templates)
+	if s.Path == "" {
+		return ts, nil
+	}
+	files, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		loaded, err := s.load(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", f, err)
+		}
+		for n, t := range loaded {
+			if _, ok := ts[n]; ok {
+				return nil, fmt.Errorf("%s: template %q is already defined", f, n)
+			}
+			ts[n] = t
+		}
+	}
+	return ts, nil
+}
+
+// Lookup returns the named template, which may be either compiled-in or loaded from s.
+func (s TemplateSource) Lookup(name string) (Template, error) {
+	ts, err := s.Templates()
+	if err != nil {
+		return nil, err
+	}
+	t, ok := ts[name]
+	if !ok {
+		names := maps.Keys(ts)
+		slices.Sort(names)
+		return nil, fmt.Errorf("no such template %q, want one of %v", name, names)
+	}
+	return t, nil
+}
+
+// Apply looks up the named template and sets flags from it, see Template.Apply.
+func (s TemplateSource) Apply(name string, fs Flags, names map[string]string) error {
+	t, err := s.Lookup(name)
+	if err != nil {
+		return err
+	}
+	klog.Infof("Using template flags %q", name)
+	return t.Apply(fs, names)
+}
+
+// ParseHashes splits a comma separated list of hex encoded hashes, as used for
+// TemplateSource.SHA256.
+func ParseHashes(s string) []string {
+	r := []string{}
+	for _, h := range strings.Split(s, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			r = append(r, h)
+		}
+	}
+	return r
+}
+
+func (s TemplateSource) files() ([]string, error) {
+	fi, err := os.Stat(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{s.Path}, nil
+	}
+	files, err := filepath.Glob(filepath.Join(s.Path, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no template files found in %s", s.Path)
+	}
+	return files, nil
+}
+
+// load reads a single template file, checking that it's pinned or signed, and that the
+// templates it contains are sensible.
+func (s TemplateSource) load(path string) (map[string]Template, error) {
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		return nil, errors.New("YAML template files are not supported, template files must be JSON")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case s.Verifier != "":
+		v, err := note.NewVerifier(s.Verifier)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template verifier: %v", err)
+		}
+		n, err := note.Open(b, note.VerifierList(v))
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify signature: %v", err)
+		}
+		b = []byte(n.Text)
+	case len(s.SHA256) > 0:
+		h := sha256.Sum256(b)
+		if !slices.ContainsFunc(s.SHA256, func(w string) bool { return strings.EqualFold(w, hex.EncodeToString(h[:])) }) {
+			return nil, fmt.Errorf("SHA256 %x is not one of the pinned hashes", h)
+		}
+	default:
+		return nil, errors.New("template files must be either pinned by SHA256 or signed")
+	}
+
+	ts := map[string]Template{}
+	if err := json.Unmarshal(b, &ts); err != nil {
+		return nil, fmt.Errorf("failed to parse templates: %v", err)
+	}
+	for n, t := range ts {
+		if err := t.check(); err != nil {
+			return nil, fmt.Errorf("template %q: %v", n, err)
+		}
+	}
+	return ts, nil
+}
@@ -0,0 +1,244 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/mod/sumdb/note"
+)
+
+func writeTemplates(t *testing.T, dir, name string, ts map[string]Template) (string, []byte) {
+	t.Helper()
+	b, err := json.Marshal(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p, b
+}
+
+func TestTemplateSource(t *testing.T) {
+	staging := maps.Clone(Templates[templateProd])
+	staging["firmware_log_url"] = "https://example.com/log/"
+	noURL := maps.Clone(staging)
+	delete(noURL, "firmware_log_url")
+	extra := maps.Clone(staging)
+	extra["something"] = "else"
+	_, approver, err := note.GenerateKey(rand.Reader, "approver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	approved := maps.Clone(staging)
+	approved[KeyFuseApprovers] = approver
+	badApprover := maps.Clone(staging)
+	badApprover[KeyFuseApprovers] = "approver+00000000+AAAA"
+
+	dir := t.TempDir()
+	p, b := writeTemplates(t, dir, "staging.json", map[string]Template{"staging": staging})
+	pin := fmt.Sprintf("%x", sha256.Sum256(b))
+	dup, _ := writeTemplates(t, t.TempDir(), "dup.json", map[string]Template{templateProd: staging})
+	missing, _ := writeTemplates(t, t.TempDir(), "missing.json", map[string]Template{"staging": noURL})
+	unknown, _ := writeTemplates(t, t.TempDir(), "unknown.json", map[string]Template{"staging": extra})
+	withApprovers, _ := writeTemplates(t, t.TempDir(), "approvers.json", map[string]Template{"staging": approved})
+	invalidApprovers, _ := writeTemplates(t, t.TempDir(), "bad_approvers.json", map[string]Template{"staging": badApprover})
+	yaml, _ := writeTemplates(t, t.TempDir(), "staging.yaml", map[string]Template{"staging": staging})
+
+	skey, vkey, err := note.GenerateKey(rand.Reader, "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := note.NewSigner(skey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := note.Sign(&note.Note{Text: string(b) + "\n"}, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedPath := filepath.Join(t.TempDir(), "signed.json")
+	if err := os.WriteFile(signedPath, signed, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := note.GenerateKey(rand.Reader, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		desc    string
+		src     TemplateSource
+		name    string
+		wantErr bool
+	}{
+		{desc: "compiled-in", name: templateCI},
+		{desc: "compiled-in alongside file", src: TemplateSource{Path: p, SHA256: []string{pin}}, name: templateProd},
+		{desc: "pinned file", src: TemplateSource{Path: p, SHA256: []string{"00", pin}}, name: "staging"},
+		{desc: "pinned directory", src: TemplateSource{Path: dir, SHA256: []string{pin}}, name: "staging"},
+		{desc: "signed file", src: TemplateSource{Path: signedPath, Verifier: vkey}, name: "staging"},
+		{desc: "no such template", src: TemplateSource{Path: p, SHA256: []string{pin}}, name: "dev", wantErr: true},
+		{desc: "not pinned or signed", src: TemplateSource{Path: p}, name: "staging", wantErr: true},
+		{desc: "wrong pin", src: TemplateSource{Path: p, SHA256: []string{"00"}}, name: "staging", wantErr: true},
+		{desc: "wrong signer", src: TemplateSource{Path: signedPath, Verifier: otherKey}, name: "staging", wantErr: true},
+		{desc: "unsigned with verifier", src: TemplateSource{Path: p, Verifier: vkey}, name: "staging", wantErr: true},
+		{desc: "redefines compiled-in", src: TemplateSource{Path: dup, SHA256: []string{hashFile(t, dup)}}, name: templateProd, wantErr: true},
+		{desc: "missing key", src: TemplateSource{Path: missing, SHA256: []string{hashFile(t, missing)}}, name: "staging", wantErr: true},
+		{desc: "unknown key", src: TemplateSource{Path: unknown, SHA256: []string{hashFile(t, unknown)}}, name: "staging", wantErr: true},
+		{desc: "fuse approvers", src: TemplateSource{Path: withApprovers, SHA256: []string{hashFile(t, withApprovers)}}, name: "staging"},
+		{desc: "invalid fuse approvers", src: TemplateSource{Path: invalidApprovers, SHA256: []string{hashFile(t, invalidApprovers)}}, name: "staging", wantErr: true},
+		{desc: "YAML file", src: TemplateSource{Path: yaml, SHA256: []string{hashFile(t, yaml)}}, name: "staging", wantErr: true},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := test.src.Lookup(test.name)
+			if (err != nil) != test.wantErr {
+				t.Errorf("Lookup(%q): got err %v, want err %t", test.name, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func hashFile(t *testing.T, p string) string {
+	t.Helper()
+	b, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(b))
+}
+
+func TestApply(t *testing.T) {
+	tmpl := Template{"log_url": "https://example.com/", "hab_target": "dev"}
+	newFlags := func() (*flag.FlagSet, Flags) {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.String("log_url", "", "")
+		fs.String("url", "", "")
+		fs.String("hab_target", "", "")
+		return fs, FlagSet(fs)
+	}
+
+	t.Run("all keys", func(t *testing.T) {
+		fs, f := newFlags()
+		if err := tmpl.Apply(f, nil); err != nil {
+			t.Fatalf("Apply: %v", err)
+		}
+		if got := fs.Lookup("hab_target").Value.String(); got != "dev" {
+			t.Errorf("--hab_target = %q, want %q", got, "dev")
+		}
+	})
+	t.Run("renamed", func(t *testing.T) {
+		fs, f := newFlags()
+		if err := tmpl.Apply(f, map[string]string{"log_url": "url"}); err != nil {
+			t.Fatalf("Apply: %v", err)
+		}
+		if got := fs.Lookup("url").Value.String(); got != tmpl["log_url"] {
+			t.Errorf("--url = %q, want %q", got, tmpl["log_url"])
+		}
+		if got := fs.Lookup("hab_target").Value.String(); got != "" {
+			t.Errorf("--hab_target = %q, want it unset", got)
+		}
+	})
+	t.Run("already set", func(t *testing.T) {
+		fs, f := newFlags()
+		if err := fs.Set("hab_target", "prod"); err != nil {
+			t.Fatal(err)
+		}
+		if err := tmpl.Apply(f, nil); err == nil {
+			t.Error("Apply: want error, got none")
+		}
+	})
+	t.Run("optional keys", func(t *testing.T) {
+		fs, f := newFlags()
+		_, approver, err := note.GenerateKey(rand.Reader, "approver")
+		if err != nil {
+			t.Fatal(err)
+		}
+		withApprovers := maps.Clone(tmpl)
+		withApprovers[KeyFuseApprovers] = approver
+		// There's no --fuse_approvers flag, and there mustn't be one.
+		if err := withApprovers.Apply(f, nil); err != nil {
+			t.Fatalf("Apply: %v", err)
+		}
+		if got := fs.Lookup("hab_target").Value.String(); got != "dev" {
+			t.Errorf("--hab_target = %q, want %q", got, "dev")
+		}
+	})
+	t.Run("unknown flag", func(t *testing.T) {
+		_, f := newFlags()
+		if err := tmpl.Apply(f, map[string]string{"hab_target": "target"}); err == nil {
+			t.Error("Apply: want error, got none")
+		}
+	})
+}
+
+func TestFuseApprovers(t *testing.T) {
+	skey, vkey, err := note.GenerateKey(rand.Reader, "approver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, err := note.GenerateKey(rand.Reader, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := note.NewSigner(skey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := note.Sign(&note.Note{Text: "approved\n"}, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		desc       string
+		approvers  string
+		wantErr    bool
+		wantSigned bool
+	}{
+		{desc: "none", wantErr: true},
+		{desc: "empty", approvers: " , ", wantErr: true},
+		{desc: "invalid", approvers: "approver+00000000+AAAA", wantErr: true},
+		{desc: "one", approvers: vkey, wantSigned: true},
+		{desc: "several", approvers: other + ", " + vkey, wantSigned: true},
+		{desc: "someone else", approvers: other},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			tmpl := Template{}
+			if test.approvers != "" {
+				tmpl[KeyFuseApprovers] = test.approvers
+			}
+			v, err := tmpl.FuseApprovers()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("FuseApprovers: got err %v, want err %t", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if _, err := note.Open(signed, v); (err == nil) != test.wantSigned {
+				t.Errorf("note.Open: got err %v, want verified %t", err, test.wantSigned)
+			}
+		})
+	}
+}
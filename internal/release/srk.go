@@ -0,0 +1,54 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/transparency-dev/armored-witness-common/release/firmware/ftlog"
+)
+
+// srkHashEnv is the build environment variable through which the SRK hash of the
+// HAB PKI is passed to firmware builds.
+const srkHashEnv = "SRK_HASH"
+
+// SRKHash returns the SRK hash which the firmware described by r was built with,
+// or an empty string if it wasn't built with one.
+func SRKHash(r *ftlog.FirmwareRelease) string {
+	for _, e := range r.Build.Envs {
+		if k, v, ok := strings.Cut(e, "="); ok && k == srkHashEnv {
+			return v
+		}
+	}
+	return ""
+}
+
+// CheckSRKHash returns an error if want is not a valid hex encoded SRK hash, or if the
+// firmware described by r was built with an SRK hash other than want.
+//
+// Since the manifest is signed by the release process, this allows an SRK hash passed
+// in via flags or a template to be cross-checked against the release environment which
+// built the firmware.
+func CheckSRKHash(r *ftlog.FirmwareRelease, want string) error {
+	if b, err := hex.DecodeString(want); err != nil || len(b) != 32 {
+		return fmt.Errorf("invalid SRK hash %q, must be 32 hex encoded bytes", want)
+	}
+	if got := SRKHash(r); got != "" && !strings.EqualFold(got, want) {
+		return fmt.Errorf("%s firmware was built for SRK hash %s, not %s", r.Component, got, want)
+	}
+	return nil
+}
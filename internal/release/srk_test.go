@@ -0,0 +1,49 @@
+// Copyright 2026 The Armored Witness authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"testing"
+
+	"github.com/transparency-dev/armored-witness-common/release/firmware/ftlog"
+)
+
+func TestCheckSRKHash(t *testing.T) {
+	const (
+		ci   = "b8ba457320663bf006accd3c57e06720e63b21ce5351cb91b4650690bb08d85a"
+		prod = "77e021cc51b5547fb0c2192fb32710bfa89b4bbaa7dab5f97fc585f673b0b236"
+	)
+	withEnvs := func(envs ...string) *ftlog.FirmwareRelease {
+		return &ftlog.FirmwareRelease{Component: ftlog.ComponentOS, Build: ftlog.Build{Envs: envs}}
+	}
+	for _, test := range []struct {
+		desc    string
+		r       *ftlog.FirmwareRelease
+		want    string
+		wantErr bool
+	}{
+		{desc: "match", r: withEnvs("DEBUG=1", "SRK_HASH="+ci), want: ci},
+		{desc: "no SRK hash in build", r: withEnvs("DEBUG=1"), want: ci},
+		{desc: "mismatch", r: withEnvs("SRK_HASH=" + prod), want: ci, wantErr: true},
+		{desc: "invalid hex", r: withEnvs(), want: "not-hex", wantErr: true},
+		{desc: "wrong length", r: withEnvs(), want: ci[:32], wantErr: true},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if err := CheckSRKHash(test.r, test.want); (err != nil) != test.wantErr {
+				t.Errorf("CheckSRKHash: got err %v, want err %t", err, test.wantErr)
+			}
+		})
+	}
+}
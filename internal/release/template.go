@@ -1,12 +1,39 @@
 package release
 
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/mod/sumdb/note"
+	"k8s.io/klog/v2"
+)
+
 const (
 	templateCI   = "ci"
 	templateProd = "prod"
 )
 
+// Template holds the flag settings needed to use a release environment, keyed by flag name.
+type Template map[string]string
+
+// KeyFuseApprovers is the optional template key listing, as comma separated note verifier
+// strings, the approvers whose fuse authorizations are accepted for the release environment.
+//
+// Unlike the other keys, it doesn't correspond to a flag: the approvers must come from
+// the template, so that whoever runs the tool can't choose them.
+const KeyFuseApprovers = "fuse_approvers"
+
+// optionalKeys are template keys which the compiled-in templates needn't set, and which
+// aren't applied to flags.
+var optionalKeys = []string{KeyFuseApprovers}
+
 var (
-	Templates = map[string]map[string]string{
+	// Templates are the compiled-in release environment templates.
+	Templates = map[string]Template{
 		templateCI: {
 			"binaries_url":          "https://api.transparency.dev/armored-witness-firmware/ci/artefacts/4/",
 			"firmware_log_url":      "https://api.transparency.dev/armored-witness-firmware/ci/log/4/",
@@ -18,6 +45,7 @@ var (
 			"os_verifier_1":         "transparency.dev-aw-os1-ci+7a0eaef3+AcsqvmrcKIbs21H2Bm2fWb6oFWn/9MmLGNc6NLJty2eQ",
 			"os_verifier_2":         "transparency.dev-aw-os2-ci+af8e4114+AbBJk5MgxRB+68KhGojhUdSt1ts5GAdRIT1Eq9zEkgQh",
 			"hab_target":            "ci",
+			"hab_srk_hash":          "b8ba457320663bf006accd3c57e06720e63b21ce5351cb91b4650690bb08d85a",
 		},
 		templateProd: {
 			"binaries_url":          "https://api.transparency.dev/armored-witness-firmware/prod/artefacts/1/",
@@ -30,6 +58,127 @@ var (
 			"os_verifier_1":         "transparency.dev-aw-os1-prod+985bdfd2+AV7mmRamQp6VC9CutzSXzqtNhYNyNmQQRcLX07F6qlC1",
 			"os_verifier_2":         "transparency.dev-aw-os2-prod+662add8c+AebLJIKJhx57T3mWmHKe0sasFnXmtIQNTGRaoj2PQLrY",
 			"hab_target":            "prod",
+			"hab_srk_hash":          "77e021cc51b5547fb0c2192fb32710bfa89b4bbaa7dab5f97fc585f673b0b236",
 		},
 	}
 )
+
+// check returns an error if t doesn't set exactly the same keys as the compiled-in
+// templates do, or any of the keys or hashes it contains are malformed.
+func (t Template) check() error {
+	want := Templates[templateProd]
+	for k := range want {
+		if t[k] == "" {
+			return fmt.Errorf("missing %q", k)
+		}
+	}
+	for k, v := range t {
+		if _, ok := want[k]; !ok && !slices.Contains(optionalKeys, k) {
+			return fmt.Errorf("unknown key %q", k)
+		}
+		switch {
+		case k == KeyFuseApprovers:
+			if _, err := t.FuseApprovers(); err != nil {
+				return err
+			}
+		case strings.HasSuffix(k, "_verifier"):
+			if _, err := note.NewVerifier(v); err != nil {
+				return fmt.Errorf("invalid %s: %v", k, err)
+			}
+		case k == "hab_srk_hash":
+			if b, err := hex.DecodeString(v); err != nil || len(b) != 32 {
+				return fmt.Errorf("invalid %s %q", k, v)
+			}
+		}
+	}
+	return nil
+}
+
+// FuseApprovers returns verifiers for the approvers listed in the template, whose fuse
+// authorizations are accepted for the release environment.
+//
+// It's an error for the template not to list any approvers, in which case devices must
+// not be fused to the environment.
+func (t Template) FuseApprovers() (note.Verifiers, error) {
+	vs := []note.Verifier{}
+	for _, s := range strings.Split(t[KeyFuseApprovers], ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		v, err := note.NewVerifier(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", KeyFuseApprovers, err)
+		}
+		vs = append(vs, v)
+	}
+	if len(vs) == 0 {
+		return nil, fmt.Errorf("template has no %s", KeyFuseApprovers)
+	}
+	return note.VerifierList(vs...), nil
+}
+
+// Flags is the set of command line flags a template is applied to.
+type Flags interface {
+	// Lookup returns the current value of the named flag, and whether the flag exists.
+	Lookup(name string) (string, bool)
+	// Set sets the named flag to value.
+	Set(name, value string) error
+}
+
+// FlagSet returns the standard library flag set fs as Flags.
+func FlagSet(fs *flag.FlagSet) Flags {
+	return stdFlags{fs}
+}
+
+type stdFlags struct {
+	fs *flag.FlagSet
+}
+
+func (s stdFlags) Lookup(name string) (string, bool) {
+	f := s.fs.Lookup(name)
+	if f == nil {
+		return "", false
+	}
+	return f.Value.String(), true
+}
+
+func (s stdFlags) Set(name, value string) error {
+	return s.fs.Set(name, value)
+}
+
+// Apply sets flags from the template t.
+//
+// If names is nil, every key in t other than the optional ones, such as KeyFuseApprovers,
+// is set on the flag of the same name. Otherwise, names maps the template keys which should
+// be used to the flags which they are set on, and other keys are ignored.
+//
+// It's an error for any of these flags to have already been set, or not to exist.
+func (t Template) Apply(fs Flags, names map[string]string) error {
+	if names == nil {
+		names = map[string]string{}
+		for k := range t {
+			if !slices.Contains(optionalKeys, k) {
+				names[k] = k
+			}
+		}
+	}
+	keys := maps.Keys(names)
+	slices.Sort(keys)
+	for _, k := range keys {
+		f := names[k]
+		v, ok := t[k]
+		if !ok {
+			return fmt.Errorf("template has no value for %q", k)
+		}
+		if c, ok := fs.Lookup(f); !ok {
+			return fmt.Errorf("template flag --%s unknown", f)
+		} else if c != "" {
+			return fmt.Errorf("cannot set both a template and --%s", f)
+		}
+		klog.Infof("Using template flag setting --%v=%v", f, v)
+		if err := fs.Set(f, v); err != nil {
+			return fmt.Errorf("failed to set template flag --%v: %v", f, err)
+		}
+	}
+	return nil
+}